@@ -0,0 +1,256 @@
+package varmq
+
+import (
+	"math"
+	"time"
+)
+
+// defaultAutoscaleInterval is how often the concurrency is adjusted if no interval is set.
+const defaultAutoscaleInterval = time.Second
+
+// AutoscaleSample is the load of a worker observed during the last autoscale interval.
+type AutoscaleSample struct {
+	// Concurrency is the current concurrency of the worker.
+	Concurrency int
+	// Pending is the number of the jobs waiting in the queue.
+	Pending int
+	// Processing is the number of the jobs being processed.
+	Processing int
+	// IdleWorkers is the number of the idle workers in the pool.
+	IdleWorkers int
+	// Started is the number of the attempts those are started during the interval.
+	Started uint64
+	// Completed is the number of the attempts those are finished during the interval, failed ones included.
+	Completed uint64
+	// ErrorRate is the ratio of the failed or panicked attempts to the completed ones, 0 if none is completed.
+	ErrorRate float64
+	// QueueWait is the average waiting time of the started jobs in the queue.
+	QueueWait time.Duration
+	// Latency is the average running time of the completed attempts.
+	Latency time.Duration
+}
+
+// AutoscalePolicy decides the next concurrency of a worker from its load.
+// A policy might keep a state between the samples, so it must not be shared by workers.
+// The copies of a worker get their own GradientPolicy, while a custom policy is shared unless a new one is passed to Copy.
+type AutoscalePolicy interface {
+	// Next returns the concurrency to be used for the next interval.
+	Next(s AutoscaleSample) int
+}
+
+// AIMDPolicy increases the concurrency additively while the jobs are waiting in the queue,
+// and decreases it multiplicatively once the worker is overloaded, i.e. the error rate or the latency exceeds its limit.
+// The concurrency is also decreased additively while the worker is underused.
+type AIMDPolicy struct {
+	// Min and Max bound the concurrency, Min defaults to 1 and Max defaults to the number of CPUs.
+	Min, Max int
+	// Increase is added to the concurrency on each interval while the jobs are waiting, default is 1.
+	Increase int
+	// Decrease is the factor the concurrency is multiplied by on overload, default is 0.5.
+	Decrease float64
+	// MaxErrorRate is the error rate those the worker is considered overloaded above, 0 disables it.
+	MaxErrorRate float64
+	// MaxLatency is the average running time those the worker is considered overloaded above, 0 disables it.
+	MaxLatency time.Duration
+	// TargetQueueWait is the average waiting time those is tolerated before increasing the concurrency,
+	// 0 means the concurrency is increased whenever jobs are waiting.
+	TargetQueueWait time.Duration
+}
+
+func (p *AIMDPolicy) Next(s AutoscaleSample) int {
+	increase := p.Increase
+	if increase <= 0 {
+		increase = 1
+	}
+
+	decrease := p.Decrease
+	if decrease <= 0 || decrease >= 1 {
+		decrease = 0.5
+	}
+
+	c := s.Concurrency
+	overloaded := (p.MaxErrorRate > 0 && s.ErrorRate > p.MaxErrorRate) ||
+		(p.MaxLatency > 0 && s.Latency > p.MaxLatency)
+
+	switch {
+	case overloaded:
+		c = int(float64(c) * decrease)
+	// the queue wait is unknown if no job is started while the jobs are waiting, so the worker is saturated
+	case s.Pending > 0 && (s.Started == 0 || s.QueueWait >= p.TargetQueueWait):
+		c += increase
+	case s.Pending == 0 && s.Processing < c:
+		c -= increase
+	}
+
+	return clampConcurrency(c, p.Min, p.Max)
+}
+
+// GradientPolicy adjusts the concurrency by the gradient of the latency, the ratio of the lowest latency
+// seen so far to the current one. The concurrency grows while the latency stays around its lowest value
+// and the jobs are waiting, and shrinks as soon as the latency rises, e.g. a downstream service slows down.
+type GradientPolicy struct {
+	// Min and Max bound the concurrency, Min defaults to 1 and Max defaults to the number of CPUs.
+	Min, Max int
+	// Tolerance is how many times the lowest latency is tolerated before shrinking, default is 1.5.
+	Tolerance float64
+	// Smoothing is the weight of the new concurrency against the current one, between 0 and 1, default is 0.2.
+	Smoothing float64
+
+	minLatency time.Duration
+	limit      float64
+}
+
+// clone returns a policy with the same settings and a fresh state, see Copy
+func (p *GradientPolicy) clone() AutoscalePolicy {
+	return &GradientPolicy{Min: p.Min, Max: p.Max, Tolerance: p.Tolerance, Smoothing: p.Smoothing}
+}
+
+func (p *GradientPolicy) Next(s AutoscaleSample) int {
+	tolerance := p.Tolerance
+	if tolerance < 1 {
+		tolerance = 1.5
+	}
+
+	smoothing := p.Smoothing
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = 0.2
+	}
+
+	// keep the fractional limit, so a small smoothing can still move the concurrency
+	if p.limit == 0 || int(math.Round(p.limit)) != s.Concurrency {
+		p.limit = float64(s.Concurrency)
+	}
+
+	if s.Latency > 0 && (p.minLatency == 0 || s.Latency < p.minLatency) {
+		p.minLatency = s.Latency
+	}
+
+	gradient := 1.0
+	if s.Latency > 0 {
+		gradient = max(0.5, min(1, tolerance*float64(p.minLatency)/float64(s.Latency)))
+	}
+
+	// failures are taken as a latency increase
+	gradient *= 1 - s.ErrorRate/2
+
+	limit := p.limit * gradient
+
+	switch {
+	case s.Pending > 0:
+		// leave a headroom for the waiting jobs
+		limit += math.Sqrt(p.limit)
+	case s.Processing < s.Concurrency:
+		limit = max(float64(s.Processing), limit-1)
+	}
+
+	minC, maxC := concurrencyBounds(p.Min, p.Max)
+	p.limit = (1-smoothing)*p.limit + smoothing*limit
+	p.limit = max(float64(minC), min(p.limit, float64(maxC)))
+
+	return int(math.Round(p.limit))
+}
+
+// concurrencyBounds returns the bounds of a policy with their defaults applied
+func concurrencyBounds(minC, maxC int) (int, int) {
+	minC = max(minC, 1)
+
+	if maxC <= 0 {
+		maxC = int(withSafeConcurrency(0))
+	}
+
+	return minC, max(minC, maxC)
+}
+
+// clampConcurrency clamps the concurrency between the bounds of a policy
+func clampConcurrency(c, minC, maxC int) int {
+	minC, maxC = concurrencyBounds(minC, maxC)
+
+	return max(minC, min(c, maxC))
+}
+
+// WithAutoscaler adjusts the concurrency of the worker periodically by the given policy, see TunePool.
+// The concurrency is shrunk by releasing the idle workers, those are released lazily if WithIdleWorkerExpiryDuration is set.
+func WithAutoscaler(policy AutoscalePolicy) ConfigFunc {
+	return func(c *configs) {
+		c.Autoscaler = policy
+	}
+}
+
+// WithAutoscaleInterval sets how often the autoscaler adjusts the concurrency, default is 1 second.
+func WithAutoscaleInterval(interval time.Duration) ConfigFunc {
+	return func(c *configs) {
+		c.AutoscaleInterval = interval
+	}
+}
+
+// goAutoscale starts a background process those adjusts the concurrency by the autoscale policy at each interval
+func (w *worker[T, R]) goAutoscale() {
+	policy := w.configs.Autoscaler
+
+	if policy == nil {
+		return
+	}
+
+	interval := w.configs.AutoscaleInterval
+	if interval <= 0 {
+		interval = defaultAutoscaleInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	w.tickers = append(w.tickers, ticker)
+
+	go func() {
+		prev := w.Metrics()
+
+		for range ticker.C {
+			cur := w.Metrics()
+			sample := newAutoscaleSample(prev, cur)
+			prev = cur
+
+			if !w.IsRunning() {
+				continue
+			}
+
+			next := policy.Next(sample)
+			if next == sample.Concurrency {
+				continue
+			}
+
+			// a failure is left to the next sample, those carries the actual concurrency if the worker is paused or stopped meanwhile
+			_ = w.TunePool(next)
+		}
+	}()
+}
+
+// newAutoscaleSample builds the sample of the interval between the given snapshots
+func newAutoscaleSample(prev, cur Metrics) AutoscaleSample {
+	failed := (cur.Failed + cur.Panicked) - (prev.Failed + prev.Panicked)
+	completed := cur.Succeeded - prev.Succeeded + failed
+
+	s := AutoscaleSample{
+		Concurrency: cur.PoolSize,
+		Pending:     cur.Pending,
+		Processing:  cur.Processing,
+		IdleWorkers: cur.IdleWorkers,
+		Started:     cur.Started - prev.Started,
+		Completed:   completed,
+		QueueWait:   averageOf(prev.QueueWait, cur.QueueWait),
+		Latency:     averageOf(prev.ProcessingTime, cur.ProcessingTime),
+	}
+
+	if completed > 0 {
+		s.ErrorRate = float64(failed) / float64(completed)
+	}
+
+	return s
+}
+
+// averageOf returns the average of the observations between the given snapshots of a histogram
+func averageOf(prev, cur Histogram) time.Duration {
+	count := cur.Count - prev.Count
+	if count == 0 {
+		return 0
+	}
+
+	return (cur.Sum - prev.Sum) / time.Duration(count)
+}
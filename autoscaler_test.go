@@ -0,0 +1,107 @@
+package varmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMDPolicy(t *testing.T) {
+	t.Run("increases additively while jobs are waiting", func(t *testing.T) {
+		p := &AIMDPolicy{Min: 1, Max: 10, Increase: 2}
+
+		assert.Equal(t, 6, p.Next(AutoscaleSample{Concurrency: 4, Pending: 5, Processing: 4, Started: 4}))
+		assert.Equal(t, 10, p.Next(AutoscaleSample{Concurrency: 9, Pending: 5, Processing: 9}), "should be clamped to max")
+	})
+
+	t.Run("tolerates the target queue wait", func(t *testing.T) {
+		p := &AIMDPolicy{Max: 10, TargetQueueWait: time.Second}
+
+		assert.Equal(t, 4, p.Next(AutoscaleSample{Concurrency: 4, Pending: 5, Processing: 4, Started: 4, QueueWait: time.Millisecond}))
+		assert.Equal(t, 5, p.Next(AutoscaleSample{Concurrency: 4, Pending: 5, Processing: 4, Started: 4, QueueWait: 2 * time.Second}))
+		assert.Equal(t, 5, p.Next(AutoscaleSample{Concurrency: 4, Pending: 5, Processing: 4}), "no job started means the worker is saturated")
+	})
+
+	t.Run("decreases multiplicatively on overload", func(t *testing.T) {
+		p := &AIMDPolicy{Max: 16, MaxErrorRate: 0.1, MaxLatency: time.Second}
+
+		assert.Equal(t, 4, p.Next(AutoscaleSample{Concurrency: 8, Pending: 5, Processing: 8, ErrorRate: 0.5}))
+		assert.Equal(t, 4, p.Next(AutoscaleSample{Concurrency: 8, Pending: 5, Processing: 8, Latency: 2 * time.Second}))
+		assert.Equal(t, 1, p.Next(AutoscaleSample{Concurrency: 1, ErrorRate: 1}), "should be clamped to min")
+	})
+
+	t.Run("decreases additively while underused", func(t *testing.T) {
+		p := &AIMDPolicy{Max: 16}
+
+		assert.Equal(t, 7, p.Next(AutoscaleSample{Concurrency: 8, Processing: 2}))
+		assert.Equal(t, 8, p.Next(AutoscaleSample{Concurrency: 8, Processing: 8}), "busy workers should be kept")
+	})
+}
+
+func TestGradientPolicy(t *testing.T) {
+	t.Run("grows while the latency is stable and jobs are waiting", func(t *testing.T) {
+		p := &GradientPolicy{Max: 100, Smoothing: 1}
+		s := AutoscaleSample{Concurrency: 16, Pending: 100, Processing: 16, Latency: 100 * time.Millisecond}
+
+		assert.Equal(t, 20, p.Next(s), "sqrt of the limit should be added as headroom")
+	})
+
+	t.Run("shrinks when the latency rises", func(t *testing.T) {
+		p := &GradientPolicy{Max: 100, Smoothing: 1, Tolerance: 1}
+
+		s := AutoscaleSample{Concurrency: 20, Processing: 20, Latency: 100 * time.Millisecond}
+		assert.Equal(t, 20, p.Next(s))
+
+		s.Latency = 200 * time.Millisecond
+		assert.Equal(t, 10, p.Next(s))
+	})
+
+	t.Run("smooths the changes", func(t *testing.T) {
+		p := &GradientPolicy{Max: 100}
+		s := AutoscaleSample{Concurrency: 4, Pending: 10, Processing: 4, Latency: time.Millisecond}
+
+		c := s.Concurrency
+		for range 10 {
+			s.Concurrency = p.Next(s)
+			assert.GreaterOrEqual(t, s.Concurrency, c, "should never shrink")
+			c = s.Concurrency
+		}
+
+		assert.Greater(t, c, 4, "small steps should be accumulated")
+	})
+
+	t.Run("is clamped to the bounds", func(t *testing.T) {
+		p := &GradientPolicy{Min: 2, Max: 8, Smoothing: 1}
+
+		assert.Equal(t, 8, p.Next(AutoscaleSample{Concurrency: 8, Pending: 100, Processing: 8}))
+		assert.Equal(t, 2, p.Next(AutoscaleSample{Concurrency: 2, Processing: 0}))
+	})
+}
+
+func TestAutoscaler(t *testing.T) {
+	t.Run("builds the sample from the metrics", func(t *testing.T) {
+		prev := Metrics{
+			Started: 10, Succeeded: 8, Failed: 1,
+			QueueWait:      Histogram{Count: 10, Sum: time.Second},
+			ProcessingTime: Histogram{Count: 9, Sum: time.Second},
+		}
+		cur := Metrics{
+			Started: 20, Succeeded: 14, Failed: 2, Panicked: 1,
+			Pending: 3, Processing: 2, PoolSize: 4, IdleWorkers: 2,
+			QueueWait:      Histogram{Count: 20, Sum: 2 * time.Second},
+			ProcessingTime: Histogram{Count: 17, Sum: 3 * time.Second},
+		}
+
+		s := newAutoscaleSample(prev, cur)
+		assert.Equal(t, 4, s.Concurrency)
+		assert.Equal(t, 3, s.Pending)
+		assert.Equal(t, uint64(10), s.Started)
+		assert.Equal(t, uint64(8), s.Completed)
+		assert.Equal(t, 0.25, s.ErrorRate)
+		assert.Equal(t, 100*time.Millisecond, s.QueueWait)
+		assert.Equal(t, 250*time.Millisecond, s.Latency)
+
+		assert.Equal(t, AutoscaleSample{Concurrency: 4, Pending: 3, Processing: 2, IdleWorkers: 2}, newAutoscaleSample(cur, cur))
+	})
+}
@@ -0,0 +1,210 @@
+package varmq
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/goptics/varmq/utils"
+)
+
+// defaultBatchSize is the max number of the jobs in a batch if no size is set.
+const defaultBatchSize = 100
+
+// maxBatchWait is how long a batch waits for more jobs if no linger is set,
+// in case the jobs those are expected to join never arrive.
+const maxBatchWait = 10 * time.Millisecond
+
+// ErrBatchResults is the error of the jobs of a batch those function returned a wrong number of results.
+var ErrBatchResults = errors.New("batch worker returned a wrong number of results")
+
+// BatchWorkerFunc represents a function that processes a batch of Jobs at once and returns a result for each of them,
+// in the same order. A returned error fails every job of the batch, unless it's a BatchErrors.
+type BatchWorkerFunc[T, R any] func([]T) ([]R, error)
+
+// BatchErrors is the error of a batch those jobs failed individually,
+// holding the error of each job in the same order, nil for the succeeded ones.
+type BatchErrors []error
+
+func (e BatchErrors) Error() string {
+	return errors.Join(e...).Error()
+}
+
+// WithBatchSize sets the max number of the jobs processed by a single call of a batch worker function, default is 100.
+func WithBatchSize(n int) ConfigFunc {
+	return func(c *configs) {
+		c.BatchSize = max(n, 1)
+	}
+}
+
+// WithBatchLinger makes a batch wait up to the given duration for more jobs to fill it.
+// By default, a batch is processed as soon as no more pending job can join it.
+func WithBatchLinger(linger time.Duration) ConfigFunc {
+	return func(c *configs) {
+		c.BatchLinger = linger
+	}
+}
+
+// batchItem is the data of a job waiting in a batch, and the channel to send its outcome back
+type batchItem[T, R any] struct {
+	data T
+	done chan execution[R]
+}
+
+// batcher collects the data of the jobs those are being processed, and runs the batch worker function
+// once the batch is full, the linger is over or no more job can join it.
+type batcher[T, R any] struct {
+	fn      BatchWorkerFunc[T, R]
+	size    int
+	linger  time.Duration
+	canJoin func(held int) bool
+	items   []batchItem[T, R]
+	// running is the number of the jobs those batch is running
+	running int
+	timer   *time.Timer
+	// gen is increased whenever a batch is taken, so the timer of a taken batch doesn't run the next one
+	gen uint64
+	mx  sync.Mutex
+}
+
+func newBatcher[T, R any](fn BatchWorkerFunc[T, R], c configs, canJoin func(held int) bool) *batcher[T, R] {
+	size := c.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+
+	return &batcher[T, R]{
+		fn:      fn,
+		size:    size,
+		linger:  c.BatchLinger,
+		canJoin: canJoin,
+	}
+}
+
+// submit adds the data into the current batch and waits for its outcome
+func (b *batcher[T, R]) submit(data T) execution[R] {
+	done := make(chan execution[R], 1)
+
+	b.mx.Lock()
+	b.items = append(b.items, batchItem[T, R]{data: data, done: done})
+
+	var batch []batchItem[T, R]
+
+	switch {
+	case len(b.items) >= b.size || !b.canJoin(b.running+len(b.items)):
+		batch = b.take()
+	case len(b.items) == 1:
+		wait := b.linger
+		if wait <= 0 {
+			wait = maxBatchWait
+		}
+
+		gen := b.gen
+		b.timer = time.AfterFunc(wait, func() { b.flush(gen) })
+	}
+	b.mx.Unlock()
+
+	// the batch is run by the job those completed it, the others wait for it
+	if batch != nil {
+		b.run(batch)
+	}
+
+	return <-done
+}
+
+// take takes the current batch, it must be called with the lock held
+func (b *batcher[T, R]) take() []batchItem[T, R] {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	batch := b.items
+	b.items = nil
+	b.running += len(batch)
+	b.gen++
+
+	return batch
+}
+
+// flush runs the current batch once its linger is over, unless it's already taken
+func (b *batcher[T, R]) flush(gen uint64) {
+	b.mx.Lock()
+	var batch []batchItem[T, R]
+
+	if b.gen == gen {
+		batch = b.take()
+	}
+	b.mx.Unlock()
+
+	if len(batch) > 0 {
+		b.run(batch)
+	}
+}
+
+// run runs the batch worker function and sends the outcome of each job back
+func (b *batcher[T, R]) run(batch []batchItem[T, R]) {
+	data := make([]T, len(batch))
+	for i, item := range batch {
+		data[i] = item.data
+	}
+
+	var results []R
+	var err error
+
+	panicErr := utils.WithSafe("batch worker", func() {
+		results, err = b.fn(data)
+	})
+
+	var errs BatchErrors
+	perItem := errors.As(err, &errs) && len(errs) == len(batch)
+
+	for i, item := range batch {
+		res := execution[R]{err: selectError(panicErr, err), panicked: panicErr != nil}
+
+		if perItem && panicErr == nil {
+			res.err = errs[i]
+		}
+
+		if res.err == nil {
+			if len(results) != len(batch) {
+				res.err = ErrBatchResults
+			} else {
+				res.result, res.hasResult = results[i], true
+			}
+		}
+
+		item.done <- res
+	}
+
+	b.mx.Lock()
+	b.running -= len(batch)
+	b.mx.Unlock()
+}
+
+// initBatcher creates the batcher if the worker function is a batch one.
+// The concurrency is raised to the batch size, since every job of a batch holds a slot of the pool while it's waiting.
+func (w *worker[T, R]) initBatcher() {
+	fn, ok := w.workerFunc.(BatchWorkerFunc[T, R])
+	if !ok {
+		return
+	}
+
+	w.batcher = newBatcher(fn, w.configs, w.canJoinBatch)
+	w.concurrency.Store(max(w.concurrency.Load(), uint32(w.batcher.size)))
+}
+
+// canJoinBatch reports whether more jobs can join the current batch, given the number of the jobs held by the batcher.
+// Without a linger, the batch doesn't wait for the jobs those are not pending yet.
+func (w *worker[T, R]) canJoinBatch(held int) bool {
+	// the jobs those are dispatched but not joined yet are on their way
+	if int(w.CurProcessing.Load()) > held {
+		return true
+	}
+
+	if w.configs.BatchLinger > 0 {
+		return w.IsRunning() && w.CurProcessing.Load() < w.concurrency.Load()
+	}
+
+	return w.canDispatch()
+}
@@ -0,0 +1,104 @@
+package varmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatcher(t *testing.T) {
+	canJoin := func(int) bool { return true }
+
+	t.Run("runs once the batch is full", func(t *testing.T) {
+		calls := make(chan []int, 2)
+		b := newBatcher(func(data []int) ([]int, error) {
+			calls <- data
+
+			results := make([]int, len(data))
+			for i, v := range data {
+				results[i] = v * 2
+			}
+
+			return results, nil
+		}, configs{BatchSize: 2, BatchLinger: time.Hour}, canJoin)
+
+		done := make(chan execution[int], 1)
+		go func() { done <- b.submit(1) }()
+
+		// wait until the first one joins the batch
+		assert.Eventually(t, func() bool {
+			b.mx.Lock()
+			defer b.mx.Unlock()
+
+			return len(b.items) == 1
+		}, time.Second, time.Millisecond)
+
+		res := b.submit(2)
+		assert.Equal(t, execution[int]{result: 4, hasResult: true}, res)
+		assert.Equal(t, execution[int]{result: 2, hasResult: true}, <-done)
+		assert.Equal(t, []int{1, 2}, <-calls)
+	})
+
+	t.Run("runs once the linger is over", func(t *testing.T) {
+		b := newBatcher(func(data []int) ([]int, error) {
+			return data, nil
+		}, configs{BatchSize: 10, BatchLinger: 20 * time.Millisecond}, canJoin)
+
+		start := time.Now()
+		res := b.submit(1)
+
+		assert.Equal(t, 1, res.result)
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("runs at once if no more job can join", func(t *testing.T) {
+		b := newBatcher(func(data []int) ([]int, error) {
+			return data, nil
+		}, configs{BatchSize: 10, BatchLinger: time.Hour}, func(int) bool { return false })
+
+		assert.Equal(t, 1, b.submit(1).result)
+	})
+
+	t.Run("fans out the errors", func(t *testing.T) {
+		failure := errors.New("failed")
+
+		run := func(fn BatchWorkerFunc[int, int]) []execution[int] {
+			done := make([]chan execution[int], 2)
+			batch := make([]batchItem[int, int], 2)
+
+			for i := range batch {
+				done[i] = make(chan execution[int], 1)
+				batch[i] = batchItem[int, int]{data: i, done: done[i]}
+			}
+
+			newBatcher(fn, configs{}, canJoin).run(batch)
+
+			return []execution[int]{<-done[0], <-done[1]}
+		}
+
+		res := run(func(data []int) ([]int, error) {
+			return nil, failure
+		})
+		assert.ErrorIs(t, res[0].err, failure)
+		assert.ErrorIs(t, res[1].err, failure)
+
+		res = run(func(data []int) ([]int, error) {
+			return []int{10, 0}, BatchErrors{nil, failure}
+		})
+		assert.Equal(t, execution[int]{result: 10, hasResult: true}, res[0])
+		assert.ErrorIs(t, res[1].err, failure)
+
+		res = run(func(data []int) ([]int, error) {
+			return []int{1}, nil
+		})
+		assert.ErrorIs(t, res[0].err, ErrBatchResults)
+
+		res = run(func(data []int) ([]int, error) {
+			panic("boom")
+		})
+		assert.True(t, res[1].panicked)
+		assert.Error(t, res[1].err)
+	})
+}
@@ -0,0 +1,226 @@
+package varmq
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// envelopeMagic starts the header of every payload encoded by a codec, e.g. "#vmq/1;json\n".
+// It can't start a JSON document, so the payloads written before the envelope existed are still decoded as JSON.
+// The header is printable, so the adapters storing the payloads as text keep working.
+const envelopeMagic = "#vmq/"
+
+// envelopeVersion is the version of the envelope header, those is written right after the magic
+const envelopeVersion = "1"
+
+// maxCodecName is the max length of a codec name, so a corrupted header is detected without scanning the whole payload
+const maxCodecName = 64
+
+var errUnknownCodec = errors.New("unknown codec")
+
+// Codec encodes the jobs of persistent and distributed queues into payloads and decodes them back.
+type Codec interface {
+	// Name identifies the codec in the envelope header of the payloads, so the consumers can detect it.
+	// It must be unique, at most 64 bytes long, and contain neither ";" nor a newline.
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes the payloads with encoding/json, it's the default codec.
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string {
+	return "json"
+}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec encodes the payloads with encoding/gob, e.g. for large binary inputs.
+// The concrete types held by interface values of the inputs and results must be registered with gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) Name() string {
+	return "gob"
+}
+
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var b bytes.Buffer
+
+	if err := gob.NewEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// WithCodec sets the codec those the jobs of persistent and distributed queues are encoded with, default is JSONCodec.
+// Consumers detect the codec of every payload by its envelope header, so they decode the payloads of the built-in codecs
+// and the configured one, whichever they're encoded with.
+func WithCodec(codec Codec) ConfigFunc {
+	return func(c *configs) {
+		c.Codec = codec
+	}
+}
+
+// WithDistributedCodec sets the codec those the producer encodes the jobs with, see WithCodec.
+func WithDistributedCodec(codec Codec) DistributedConfigFunc {
+	return func(c *distributedConfigs) {
+		c.Codec = codec
+	}
+}
+
+// encodePayload encodes the value with the codec, JSONCodec if it's nil, and prepends the envelope header
+func encodePayload(codec Codec, v any) ([]byte, error) {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	name := codec.Name()
+	if name == "" || len(name) > maxCodecName || strings.ContainsAny(name, ";\n") {
+		return nil, fmt.Errorf("invalid codec name %q", name)
+	}
+
+	body, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	header := envelopeMagic + envelopeVersion + ";" + name + "\n"
+	payload := make([]byte, 0, len(header)+len(body))
+	payload = append(payload, header...)
+
+	return append(payload, body...), nil
+}
+
+// decodePayload decodes the payload with the codec named by its envelope header, a built-in one or the given one.
+// Payloads without an envelope are decoded as JSON.
+func decodePayload(codec Codec, data []byte, v any) error {
+	if !bytes.HasPrefix(data, []byte(envelopeMagic)) {
+		return json.Unmarshal(data, v)
+	}
+
+	rest := data[len(envelopeMagic):]
+
+	end := bytes.IndexByte(rest[:min(len(rest), len(envelopeVersion)+1+maxCodecName+1)], '\n')
+	if end < 0 {
+		return errors.New("invalid envelope header")
+	}
+
+	version, name, ok := strings.Cut(string(rest[:end]), ";")
+	if !ok {
+		return errors.New("invalid envelope header")
+	}
+
+	if version != envelopeVersion {
+		return fmt.Errorf("unsupported envelope version %s", version)
+	}
+
+	body := rest[end+1:]
+
+	switch {
+	case codec != nil && codec.Name() == name:
+		return codec.Unmarshal(body, v)
+	case name == JSONCodec{}.Name():
+		return JSONCodec{}.Unmarshal(body, v)
+	case name == GobCodec{}.Name():
+		return GobCodec{}.Unmarshal(body, v)
+	}
+
+	return fmt.Errorf("%w: %s", errUnknownCodec, name)
+}
+
+// errorCodes are the errors those are restored as themselves once they're decoded, by their codes
+var errorCodes = []struct {
+	code string
+	err  error
+}{
+	{"timeout", ErrJobTimeout},
+	{"cancelled", ErrJobCancelled},
+	{"queue_full", ErrQueueFull},
+	{"purged", ErrJobPurged},
+}
+
+// errorView is the serialized error, the errors of errorCodes are restored by their codes, so errors.Is keeps working
+type errorView struct {
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+}
+
+func newErrorView(err error) *errorView {
+	if err == nil {
+		return nil
+	}
+
+	view := &errorView{Message: err.Error()}
+
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			view.Code = c.code
+			break
+		}
+	}
+
+	return view
+}
+
+// error returns the decoded error, nil if there is none
+func (e *errorView) error() error {
+	if e == nil || (e.Message == "" && e.Code == "") {
+		return nil
+	}
+
+	decoded := &decodedError{message: e.Message}
+
+	for _, c := range errorCodes {
+		if c.code == e.Code {
+			decoded.cause = c.err
+			break
+		}
+	}
+
+	return decoded
+}
+
+// decodedError is an error decoded from a payload, it unwraps to the error of its code if any
+type decodedError struct {
+	message string
+	cause   error
+}
+
+func (e *decodedError) Error() string {
+	return e.message
+}
+
+func (e *decodedError) Unwrap() error {
+	return e.cause
+}
+
+// resultView is the serialized Result, those error is kept as an errorView to survive the round trip
+type resultView[R any] struct {
+	JobId string     `json:"JobId"`
+	Data  R          `json:"Data"`
+	Err   *errorView `json:"Err,omitempty"`
+}
+
+func newResultView[R any](r Result[R]) resultView[R] {
+	return resultView[R]{JobId: r.JobId, Data: r.Data, Err: newErrorView(r.Err)}
+}
+
+func (v resultView[R]) result() Result[R] {
+	return Result[R]{JobId: v.JobId, Data: v.Data, Err: v.Err.error()}
+}
@@ -0,0 +1,182 @@
+package varmq
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// upperCodec is a custom codec those wraps JSONCodec
+type upperCodec struct {
+	JSONCodec
+}
+
+func (upperCodec) Name() string {
+	return "upper"
+}
+
+func TestCodec(t *testing.T) {
+	codecs := map[string]Codec{
+		"json": JSONCodec{},
+		"gob":  GobCodec{},
+	}
+
+	for name, codec := range codecs {
+		t.Run(name+" round trips a job", func(t *testing.T) {
+			j := newJob[[]byte, int]([]byte{0, 1, 2}, jobConfigs{Id: "job", Key: "tenant"})
+			j.SaveAndSendError(fmt.Errorf("slow: %w", ErrJobTimeout))
+
+			data, err := j.encode(codec)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(data), "#vmq/1;"+name+"\n"))
+
+			// the consumer detects the codec by the envelope, whichever it's configured with
+			parsed, err := parseToJob[[]byte, int](data, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, "job", parsed.ID())
+			assert.Equal(t, []byte{0, 1, 2}, parsed.Data())
+			assert.Equal(t, "tenant", parsed.Key())
+			assert.Equal(t, "job", parseJobId(data, nil))
+
+			_, err = parsed.outcomeResult()
+			assert.EqualError(t, err, "slow: job timed out")
+			assert.ErrorIs(t, err, ErrJobTimeout, "the errors of varmq should be restored")
+		})
+	}
+
+	t.Run("payload without envelope is decoded as JSON", func(t *testing.T) {
+		j := newJob[string, int]("data", jobConfigs{Id: "job"})
+		j.SaveAndSendResult(42)
+
+		data, err := j.Json()
+		assert.NoError(t, err)
+
+		parsed, err := parseToJob[string, int](data, GobCodec{})
+		assert.NoError(t, err)
+		assert.Equal(t, "data", parsed.Data())
+
+		result, err := parsed.outcomeResult()
+		assert.NoError(t, err)
+		assert.Equal(t, 42, result)
+	})
+
+	t.Run("custom codec is detected by its name", func(t *testing.T) {
+		j := newJob[string, int]("data", jobConfigs{Id: "job"})
+
+		data, err := j.encode(upperCodec{})
+		assert.NoError(t, err)
+
+		_, err = parseToJob[string, int](data, nil)
+		assert.ErrorIs(t, err, errUnknownCodec)
+
+		parsed, err := parseToJob[string, int](data, upperCodec{})
+		assert.NoError(t, err)
+		assert.Equal(t, "data", parsed.Data())
+	})
+
+	t.Run("invalid envelope is not decoded", func(t *testing.T) {
+		for _, data := range []string{"#vmq/1;json", "#vmq/2;json\n{}", "#vmq/json\n{}"} {
+			_, err := parseToJob[string, int]([]byte(data), nil)
+			assert.Error(t, err, data)
+		}
+
+		_, err := encodePayload(namedCodec("a;b"), nil)
+		assert.Error(t, err, "codec name should not break the envelope")
+	})
+
+	t.Run("stored result restores the errors of varmq", func(t *testing.T) {
+		j := newJob[int, int](1, jobConfigs{Id: "job"})
+		j.SaveAndSendError(ErrJobCancelled)
+
+		data, err := encodeResult[int, int](j, nil)
+		assert.NoError(t, err)
+
+		r, err := parseStoredResult[int](data, nil)
+		assert.NoError(t, err)
+		assert.ErrorIs(t, r.Err, ErrJobCancelled)
+
+		j = newJob[int, int](1, jobConfigs{Id: "job"})
+		j.SaveAndSendError(errors.New("failed"))
+
+		data, _ = encodeResult[int, int](j, nil)
+		r, _ = parseStoredResult[int](data, nil)
+		assert.EqualError(t, r.Err, "failed")
+		assert.NotErrorIs(t, r.Err, ErrJobCancelled)
+	})
+
+	t.Run("stored result is encoded by the codec", func(t *testing.T) {
+		j := newJob[int, int](1, jobConfigs{Id: "job"})
+		j.SaveAndSendResult(2)
+
+		data, err := encodeResult[int, int](j, namedCodec("custom"))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(data), "#vmq/1;custom\n"))
+
+		r, err := parseStoredResult[int](data, namedCodec("custom"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, r.Data)
+
+		_, err = parseStoredResult[int](data, nil)
+		assert.ErrorIs(t, err, errUnknownCodec)
+
+		// the results stored before the envelope existed are still decoded
+		r, err = parseStoredResult[int]([]byte(`{"job_id":"job","data":3}`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, r.Data)
+	})
+
+	t.Run("worker consumes the jobs of a producer with another codec", func(t *testing.T) {
+		dq := newMockDistributedQueue()
+		processed := make(chan string, 1)
+
+		q := NewVoidWorker(func(data string) {
+			processed <- data
+		}).WithDistributedQueue(dq)
+		defer q.Close()
+
+		producer := NewDistributedQueue[string, any](dq, WithDistributedCodec(GobCodec{}))
+		assert.True(t, producer.Add("hello", WithJobId("job")))
+
+		select {
+		case data := <-processed:
+			assert.Equal(t, "hello", data)
+		case <-time.After(time.Second):
+			t.Fatal("job should be processed")
+		}
+	})
+
+	t.Run("worker encodes the requeued jobs with its codec", func(t *testing.T) {
+		mq := newMockPersistentQueue()
+
+		q := NewWorker(func(data int) (int, error) {
+			return data, nil
+		}, WithCodec(GobCodec{})).WithPersistentQueue(mq)
+		defer q.Close()
+
+		job, ok := q.Add(1, WithJobId("job"))
+		assert.True(t, ok)
+
+		_, err := job.Result()
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(mq.enqueued[0]), "#vmq/1;gob\n"))
+	})
+}
+
+// namedCodec is a JSON codec with the given name
+type namedCodec string
+
+func (c namedCodec) Name() string {
+	return string(c)
+}
+
+func (namedCodec) Marshal(v any) ([]byte, error) {
+	return JSONCodec{}.Marshal(v)
+}
+
+func (namedCodec) Unmarshal(data []byte, v any) error {
+	return JSONCodec{}.Unmarshal(data, v)
+}
@@ -13,8 +13,31 @@ type configs struct {
 	Cache                    ICache
 	CleanupCacheInterval     time.Duration
 	JobIdGenerator           func() string
+	HasJobIdGenerator        bool
 	IdleWorkerExpiryDuration time.Duration
 	MinIdleWorkerRatio       uint8
+	DeadLetterQueue          IQueue
+	MaxPending               int
+	Overflow                 OverflowPolicy
+	Autoscaler               AutoscalePolicy
+	AutoscaleInterval        time.Duration
+	RateLimiter              RateLimiter
+	MaxConcurrencyPerKey     int
+	KeyRateLimiter           func() RateLimiter
+	SerialKeys               bool
+	BatchSize                int
+	BatchLinger              time.Duration
+	ResultBackend            IResultBackend
+	ResultTTL                time.Duration
+	ReplyQueues              ReplyQueueOpener
+	NackRequeue              bool
+	NackDelay                time.Duration
+	Lease                    time.Duration
+	Quarantine               func(msg PoisonMessage) error
+	QuarantinePolicy         QuarantinePolicy
+	Codec                    Codec
+	// JobConfigs holds the default job configs applied to every job of the worker
+	JobConfigs jobConfigs
 }
 
 func newConfig() configs {
@@ -38,6 +61,9 @@ func mergeConfigs(c configs, cs ...any) configs {
 		switch config := config.(type) {
 		case ConfigFunc:
 			config(&c)
+		case JobConfigFunc:
+			// job configs passed to the worker are used as defaults for every job
+			config(&c.JobConfigs)
 		case int:
 			c.Concurrency = withSafeConcurrency(config)
 		}
@@ -92,6 +118,7 @@ func WithAutoCleanupCache(duration time.Duration) ConfigFunc {
 func WithJobIdGenerator(fn func() string) ConfigFunc {
 	return func(c *configs) {
 		c.JobIdGenerator = fn
+		c.HasJobIdGenerator = true
 	}
 }
 
@@ -102,3 +129,20 @@ func withSafeConcurrency(concurrency int) uint32 {
 	}
 	return uint32(concurrency)
 }
+
+// cloneable is implemented by the built-in autoscale policies and rate limiters those keep a state
+type cloneable[T any] interface {
+	clone() T
+}
+
+// cloneStates gives the configs copied from the given ones their own autoscaler and rate limiter, unless they're replaced,
+// since a stateful one must not be shared by workers. Custom ones are shared as they are.
+func (c *configs) cloneStates(from configs) {
+	if p, ok := c.Autoscaler.(cloneable[AutoscalePolicy]); ok && c.Autoscaler == from.Autoscaler {
+		c.Autoscaler = p.clone()
+	}
+
+	if l, ok := c.RateLimiter.(cloneable[RateLimiter]); ok && c.RateLimiter == from.RateLimiter {
+		c.RateLimiter = l.clone()
+	}
+}
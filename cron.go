@@ -0,0 +1,269 @@
+package varmq
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes when a scheduled job should run.
+type Schedule interface {
+	// Next returns the next run time after the given time.
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule runs at a fixed interval
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Every returns a schedule those run every given interval.
+// The interval is rounded up to at least one second.
+func Every(interval time.Duration) Schedule {
+	return intervalSchedule{interval: max(interval, time.Second)}
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// cronField is the bit set of allowed values of a cron field
+type cronField uint64
+
+func (f cronField) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+// cronSchedule is a parsed cron expression
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow cronField
+	// domStar and dowStar are true if the field is not restricted, used to decide how the days are matched
+	domStar, dowStar bool
+}
+
+type cronBounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondBounds = cronBounds{min: 0, max: 59}
+	minuteBounds = cronBounds{min: 0, max: 59}
+	hourBounds   = cronBounds{min: 0, max: 23}
+	domBounds    = cronBounds{min: 1, max: 31}
+	monthBounds  = cronBounds{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as sunday as well and folded into 0
+	dowBounds = cronBounds{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron parses a standard cron expression and returns its schedule.
+//
+// It accepts 5 fields (minute hour day-of-month month day-of-week),
+// 6 fields with a leading second field, descriptors like @daily or @hourly,
+// and @every <duration> for fixed intervals.
+// Each field supports *, ?, values, ranges (a-b), steps (*/n, a-b/n, a/n), lists (a,b,c),
+// month names (JAN-DEC) and day names (SUN-SAT).
+//
+// Example:
+//
+//	schedule, err := ParseCron("30 2 * * MON-FRI") // at 02:30 on every weekday
+func ParseCron(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if interval, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron interval %q: %w", interval, err)
+		}
+
+		return Every(d), nil
+	}
+
+	if descriptor, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	s := &cronSchedule{}
+	var err error
+
+	targets := []struct {
+		field  *cronField
+		bounds cronBounds
+	}{
+		{&s.second, secondBounds},
+		{&s.minute, minuteBounds},
+		{&s.hour, hourBounds},
+		{&s.dom, domBounds},
+		{&s.month, monthBounds},
+		{&s.dow, dowBounds},
+	}
+
+	for i, target := range targets {
+		if *target.field, err = parseCronField(fields[i], target.bounds); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+
+	// fold 7 into 0 as both mean sunday
+	if s.dow.has(7) {
+		s.dow = (s.dow &^ (1 << 7)) | 1
+	}
+
+	// like vixie cron, a field starting with * (e.g. */2) is treated as unrestricted
+	s.domStar = isCronStar(fields[3]) || strings.HasPrefix(fields[3], "*")
+	s.dowStar = isCronStar(fields[5]) || strings.HasPrefix(fields[5], "*")
+
+	return s, nil
+}
+
+func isCronStar(field string) bool {
+	return field == "*" || field == "?"
+}
+
+func parseCronField(field string, bounds cronBounds) (cronField, error) {
+	var f cronField
+
+	for part := range strings.SplitSeq(field, ",") {
+		bits, err := parseCronRange(part, bounds)
+		if err != nil {
+			return 0, err
+		}
+
+		f |= bits
+	}
+
+	return f, nil
+}
+
+func parseCronRange(part string, bounds cronBounds) (cronField, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+	start, end, step := bounds.min, bounds.max, 1
+
+	if hasStep {
+		var err error
+		if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+			return 0, fmt.Errorf("invalid step %q", part)
+		}
+	}
+
+	switch {
+	case isCronStar(rangePart):
+	case strings.Contains(rangePart, "-"):
+		lo, hi, _ := strings.Cut(rangePart, "-")
+		var err error
+
+		if start, err = parseCronValue(lo, bounds); err != nil {
+			return 0, err
+		}
+
+		if end, err = parseCronValue(hi, bounds); err != nil {
+			return 0, err
+		}
+	default:
+		var err error
+		if start, err = parseCronValue(rangePart, bounds); err != nil {
+			return 0, err
+		}
+
+		// a single value without step means only that value, otherwise it's the start of the range
+		if !hasStep {
+			end = start
+		}
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("invalid range %q", part)
+	}
+
+	var f cronField
+	for v := start; v <= end; v += step {
+		f |= 1 << uint(v)
+	}
+
+	return f, nil
+}
+
+func parseCronValue(value string, bounds cronBounds) (int, error) {
+	if v, ok := bounds.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+
+	if v < bounds.min || v > bounds.max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d]", v, bounds.min, bounds.max)
+	}
+
+	return v, nil
+}
+
+// Next returns the next time matching the schedule after the given time.
+// It returns the zero time if there is no matching time within the next five years.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !s.month.has(int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		// the hours and minutes are skipped on the absolute clock, since the wall clock repeats an hour
+		// once the daylight saving time ends, so building them by time.Date might go back in time
+		case !s.hour.has(t.Hour()):
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+		case !s.minute.has(t.Minute()):
+			t = t.Add(time.Minute - time.Duration(t.Second())*time.Second)
+		case !s.second.has(t.Second()):
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows the standard cron behavior,
+// if both day of month and day of week are restricted, matching any of them is enough.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom.has(t.Day())
+	dowMatch := s.dow.has(int(t.Weekday()))
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
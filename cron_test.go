@@ -0,0 +1,113 @@
+package varmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC) // wednesday
+
+	tests := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"every minute", "* * * * *", base, base.Add(time.Minute)},
+		{"specific time", "30 2 * * *", base, time.Date(2025, time.January, 16, 2, 30, 0, 0, time.UTC)},
+		{"with seconds", "15 * * * * *", base, base.Add(15 * time.Second)},
+		{"step", "*/20 * * * *", base, time.Date(2025, time.January, 15, 10, 40, 0, 0, time.UTC)},
+		{"range with step", "0 9-17/4 * * *", base, time.Date(2025, time.January, 15, 13, 0, 0, 0, time.UTC)},
+		{"list", "0 8,12 * * *", base, time.Date(2025, time.January, 15, 12, 0, 0, 0, time.UTC)},
+		{"weekday names", "0 9 * * MON-FRI", time.Date(2025, time.January, 17, 10, 0, 0, 0, time.UTC), time.Date(2025, time.January, 20, 9, 0, 0, 0, time.UTC)},
+		{"month names", "0 0 1 jun *", base, time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", base, time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"day of month or day of week", "0 0 20 * 5", base, time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", base, time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"daily descriptor", "@daily", base, time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{"hourly descriptor", "@hourly", base, time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"every descriptor", "@every 90m", base, base.Add(90 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCron(tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, s.Next(tt.from))
+		})
+	}
+
+	t.Run("invalid expressions", func(t *testing.T) {
+		for _, expr := range []string{
+			"",
+			"* * * *",
+			"* * * * * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"* * * 13 *",
+			"* * * * 8",
+			"*/0 * * * *",
+			"10-5 * * * *",
+			"a * * * *",
+			"@every nope",
+		} {
+			_, err := ParseCron(expr)
+			assert.Error(t, err, "expression %q should be invalid", expr)
+		}
+	})
+
+	t.Run("impossible date returns zero time", func(t *testing.T) {
+		s, err := ParseCron("0 0 30 2 *")
+		assert.NoError(t, err)
+		assert.True(t, s.Next(base).IsZero())
+	})
+
+	t.Run("daylight saving time ends", func(t *testing.T) {
+		ny, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skip("time zone database is not available")
+		}
+
+		// 2024-11-03 01:30 EST, the wall clock has already gone through 01:30 EDT
+		from := time.Date(2024, time.November, 3, 6, 30, 0, 0, time.UTC).In(ny)
+
+		s, _ := ParseCron("45 1 * * *")
+		next := s.Next(from)
+		assert.True(t, next.After(from), "next %s should be after %s", next, from)
+		assert.Equal(t, from.Add(15*time.Minute), next)
+
+		// 2024-11-03 01:30 EDT, the next hour on the wall clock is 01:00 EST
+		from = time.Date(2024, time.November, 3, 5, 30, 0, 0, time.UTC).In(ny)
+
+		s, _ = ParseCron("0 * * * *")
+		assert.Equal(t, from.Add(30*time.Minute), s.Next(from))
+	})
+
+	t.Run("daylight saving time starts", func(t *testing.T) {
+		ny, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skip("time zone database is not available")
+		}
+
+		// 2024-03-10 01:30 EST, the wall clock jumps from 02:00 to 03:00
+		from := time.Date(2024, time.March, 10, 6, 30, 0, 0, time.UTC).In(ny)
+
+		s, _ := ParseCron("0 * * * *")
+		assert.Equal(t, time.Date(2024, time.March, 10, 3, 0, 0, 0, ny), s.Next(from))
+
+		// the skipped time doesn't exist, so it's the next day
+		s, _ = ParseCron("30 2 * * *")
+		assert.Equal(t, time.Date(2024, time.March, 11, 2, 30, 0, 0, ny), s.Next(from))
+	})
+}
+
+func TestEvery(t *testing.T) {
+	base := time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, base.Add(5*time.Minute), Every(5*time.Minute).Next(base))
+	assert.Equal(t, base.Add(time.Second), Every(time.Millisecond).Next(base), "interval should be at least one second")
+}
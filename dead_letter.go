@@ -0,0 +1,163 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errNoDeadLetterQueue = errors.New("dead letter queue is not configured")
+
+// DeadLetter represents a job that failed terminally and has been moved to the dead letter queue.
+type DeadLetter[T any] struct {
+	// JobId is the id of the failed job, it's empty for unparsable payloads.
+	JobId string `json:"job_id"`
+	// Input is the input data of the failed job.
+	Input T `json:"input"`
+	// Payload is the raw payload of the job, only set if the payload couldn't be parsed.
+	Payload []byte `json:"payload,omitempty"`
+	// Error is the text of the error the job failed with.
+	Error string `json:"error"`
+	// Attempts is the number of times the job has been attempted.
+	Attempts int `json:"attempts"`
+	// Priority is the priority the job has been enqueued with.
+	Priority int `json:"priority,omitempty"`
+	// CreatedAt is the time when the job was created.
+	CreatedAt time.Time `json:"created_at"`
+	// FailedAt is the time when the job was moved to the dead letter queue.
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// IsUnparsable returns true if the dead letter holds a payload those couldn't be parsed to a job.
+func (dl DeadLetter[T]) IsUnparsable() bool {
+	return dl.Payload != nil
+}
+
+// WithDeadLetterQueue moves the jobs those fail terminally (returning an error or panicking after all the attempts,
+// or having an unparsable payload) into the given queue as a serialized DeadLetter, instead of discarding them.
+func WithDeadLetterQueue(q IQueue) ConfigFunc {
+	return func(c *configs) {
+		c.DeadLetterQueue = q
+	}
+}
+
+// ParseDeadLetter decodes a dead letter of the dead letter queue, those is encoded by the codec of the worker, see WithCodec.
+// The dead letters of the built-in codecs are decoded whichever the given codec is, it can be nil.
+func ParseDeadLetter[T any](data []byte, codec Codec) (DeadLetter[T], error) {
+	var dl DeadLetter[T]
+
+	if err := decodePayload(codec, data, &dl); err != nil {
+		return dl, fmt.Errorf("failed to parse dead letter: %w", err)
+	}
+
+	return dl, nil
+}
+
+// moveToDeadLetterQueue moves the failed job into the dead letter queue if configured, and returns true if it has been moved
+func (w *worker[T, R]) moveToDeadLetterQueue(j iJob[T, R], err error) bool {
+	if w.configs.DeadLetterQueue == nil {
+		return false
+	}
+
+	return w.enqueueDeadLetter(DeadLetter[T]{
+		JobId:     j.ID(),
+		Input:     j.Data(),
+		Error:     err.Error(),
+		Attempts:  j.Attempts(),
+		Priority:  j.Priority(),
+		CreatedAt: j.CreatedAt(),
+		FailedAt:  time.Now(),
+	})
+}
+
+// movePayloadToDeadLetterQueue moves the unparsable payload into the dead letter queue if configured
+// It returns true if the payload has been moved.
+func (w *worker[T, R]) movePayloadToDeadLetterQueue(payload []byte, err error) bool {
+	if w.configs.DeadLetterQueue == nil {
+		return false
+	}
+
+	return w.enqueueDeadLetter(DeadLetter[T]{
+		Payload:  payload,
+		Error:    err.Error(),
+		FailedAt: time.Now(),
+	})
+}
+
+func (w *worker[T, R]) enqueueDeadLetter(dl DeadLetter[T]) bool {
+	data, err := encodePayload(w.configs.Codec, dl)
+
+	if err != nil {
+		return false
+	}
+
+	return w.configs.DeadLetterQueue.Enqueue(data)
+}
+
+// replayDeadLetters dequeues all the dead letters and enqueues the ones matching the filter as new jobs.
+// The dead letters those don't match the filter or can't be replayed are put back into the dead letter queue.
+func (w *worker[T, R]) replayDeadLetters(filter func(DeadLetter[T]) bool) (int, error) {
+	dlq := w.configs.DeadLetterQueue
+
+	if dlq == nil {
+		return 0, errNoDeadLetterQueue
+	}
+
+	replayed := 0
+	_, acknowledgeable := w.Queue.(IAcknowledgeable)
+
+	// take a snapshot of the length, so the put back dead letters are not visited again
+	for range dlq.Len() {
+		var v any
+		var ok bool
+		var ackId string
+
+		switch q := dlq.(type) {
+		case IAcknowledgeable:
+			v, ok, ackId = q.DequeueWithAckId()
+		default:
+			v, ok = q.Dequeue()
+		}
+
+		if !ok {
+			break
+		}
+
+		data, _ := v.([]byte)
+		dl, err := ParseDeadLetter[T](data, w.configs.Codec)
+
+		replayable := err == nil && !dl.IsUnparsable() && (dl.JobId != "" || !acknowledgeable)
+
+		if replayable && (filter == nil || filter(dl)) && w.replay(dl) {
+			replayed++
+		} else {
+			dlq.Enqueue(v)
+		}
+
+		if ackId != "" {
+			dlq.(IAcknowledgeable).Acknowledge(ackId)
+		}
+	}
+
+	return replayed, nil
+}
+
+// replay enqueues the dead letter as a new job into the worker's queue.
+// It's admitted like an added job, so a bounded queue is not overfilled, see WithMaxPending.
+// It never waits for room though, the dead letter is kept instead.
+func (w *worker[T, R]) replay(dl DeadLetter[T]) bool {
+	j := newJob[T, R](dl.Input, loadJobConfigs(w.configs, WithJobId(dl.JobId)))
+	j.SetPriority(dl.Priority)
+
+	if err := w.addJob(context.Background(), j, false, func() bool {
+		return w.enqueueJob(j)
+	}); err != nil {
+		return false
+	}
+
+	j.ChangeStatusFrom(created, queued)
+	w.cacheJob(j)
+	w.notifyToPullNextJobs()
+	return true
+}
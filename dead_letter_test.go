@@ -0,0 +1,183 @@
+package varmq
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/goptics/varmq/internal/collections"
+)
+
+func TestDeadLetterQueue(t *testing.T) {
+	t.Run("failed job is moved to the dead letter queue", func(t *testing.T) {
+		dlq := collections.NewQueue[any]()
+
+		q := NewWorker(func(data int) (int, error) {
+			return 0, errors.New("failed")
+		}, WithDeadLetterQueue(dlq), WithRetry(2, nil)).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(42, WithJobId("job-1"))
+		_, err := job.Result()
+		assert.Error(t, err)
+
+		q.WaitUntilFinished()
+		assert.Equal(t, 1, dlq.Len(), "failed job should be moved to the dead letter queue")
+
+		v, _ := dlq.Dequeue()
+		dl, err := ParseDeadLetter[int](v.([]byte), nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "job-1", dl.JobId)
+		assert.Equal(t, 42, dl.Input)
+		assert.Equal(t, "failed", dl.Error)
+		assert.Equal(t, 2, dl.Attempts)
+		assert.False(t, dl.CreatedAt.IsZero())
+		assert.False(t, dl.FailedAt.Before(dl.CreatedAt))
+		assert.False(t, dl.IsUnparsable())
+	})
+
+	t.Run("panicked job is moved to the dead letter queue", func(t *testing.T) {
+		dlq := collections.NewQueue[any]()
+
+		q := NewVoidWorker(func(_ int) {
+			panic("boom")
+		}, WithDeadLetterQueue(dlq)).BindQueue()
+		defer q.Close()
+
+		q.Add(1)
+		q.WaitUntilFinished()
+
+		v, ok := dlq.Dequeue()
+		assert.True(t, ok)
+
+		dl, _ := ParseDeadLetter[int](v.([]byte), nil)
+		assert.Contains(t, dl.Error, "boom")
+	})
+
+	t.Run("succeeded job is not moved to the dead letter queue", func(t *testing.T) {
+		dlq := collections.NewQueue[any]()
+
+		q := NewVoidWorker(func(_ int) {}, WithDeadLetterQueue(dlq)).BindQueue()
+		defer q.Close()
+
+		q.Add(1)
+		q.WaitUntilFinished()
+
+		assert.Equal(t, 0, dlq.Len())
+	})
+
+	t.Run("unparsable payload is moved to the dead letter queue and acknowledged", func(t *testing.T) {
+		dlq := collections.NewQueue[any]()
+		mq := newMockPersistentQueue()
+		mq.Enqueue([]byte("not a job"))
+
+		q := NewVoidWorker(func(_ int) {}, WithDeadLetterQueue(dlq)).WithPersistentQueue(mq)
+		defer q.Close()
+
+		q.WaitUntilFinished()
+
+		v, ok := dlq.Dequeue()
+		assert.True(t, ok)
+
+		dl, _ := ParseDeadLetter[int](v.([]byte), nil)
+		assert.True(t, dl.IsUnparsable())
+		assert.Equal(t, []byte("not a job"), dl.Payload)
+		assert.Equal(t, 0, mq.Unacked(), "moved payload should be acknowledged")
+	})
+
+	t.Run("replay dead letters", func(t *testing.T) {
+		dlq := collections.NewQueue[any]()
+		var fail atomic.Bool
+		fail.Store(true)
+
+		q := NewWorker(func(data int) (int, error) {
+			if fail.Load() {
+				return 0, errors.New("failed")
+			}
+
+			return data * 2, nil
+		}, WithDeadLetterQueue(dlq), WithCache(new(sync.Map))).BindQueue()
+		defer q.Close()
+
+		q.Add(1, WithJobId("job-1"))
+		q.Add(2, WithJobId("job-2"))
+		q.WaitUntilFinished()
+		assert.Equal(t, 2, dlq.Len())
+
+		fail.Store(false)
+
+		replayed, err := q.ReplayDeadLetters(func(dl DeadLetter[int]) bool {
+			return dl.JobId == "job-2"
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, replayed)
+		assert.Equal(t, 1, dlq.Len(), "not matching dead letter should be kept")
+
+		job, err := q.JobById("job-2")
+		assert.NoError(t, err)
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+
+		replayed, err = q.ReplayDeadLetters(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, replayed)
+		assert.Equal(t, 0, dlq.Len())
+	})
+
+	t.Run("replay doesn't overfill a bounded queue", func(t *testing.T) {
+		dlq := collections.NewQueue[any]()
+
+		for _, id := range []string{"job-1", "job-2"} {
+			data, err := encodePayload(nil, DeadLetter[int]{JobId: id, Input: 1})
+			assert.NoError(t, err)
+			dlq.Enqueue(data)
+		}
+
+		w := NewVoidWorker(func(_ int) {}, WithDeadLetterQueue(dlq), WithMaxPending(1))
+		w.Pause()
+		q := w.BindQueue()
+		defer q.Close()
+
+		replayed, err := q.ReplayDeadLetters(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, replayed)
+		assert.Equal(t, 1, q.NumPending())
+		assert.Equal(t, 1, dlq.Len(), "dead letter those has no room should be kept")
+	})
+
+	t.Run("dead letters are encoded with the codec", func(t *testing.T) {
+		dlq := collections.NewQueue[any]()
+
+		q := NewVoidWorker(func(_ int) {
+			panic("failed")
+		}, WithDeadLetterQueue(dlq), WithCodec(namedCodec("custom"))).BindQueue()
+		defer q.Close()
+
+		q.Add(1, WithJobId("job-1"))
+		q.WaitUntilFinished()
+
+		v, ok := dlq.Dequeue()
+		assert.True(t, ok)
+		assert.True(t, bytes.HasPrefix(v.([]byte), []byte("#vmq/1;custom\n")))
+
+		dl, err := ParseDeadLetter[int](v.([]byte), namedCodec("custom"))
+		assert.NoError(t, err)
+		assert.Equal(t, "job-1", dl.JobId)
+		assert.Equal(t, 1, dl.Input)
+	})
+
+	t.Run("replay without dead letter queue", func(t *testing.T) {
+		q := NewVoidWorker(func(_ int) {}).BindQueue()
+		defer q.Close()
+
+		_, err := q.ReplayDeadLetters(nil)
+		assert.ErrorIs(t, err, errNoDeadLetterQueue)
+	})
+}
@@ -0,0 +1,206 @@
+package varmq
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// delayLookahead is the max number of the not due jobs of acknowledgeable queues those are held by the worker,
+// unless the queue takes them back, see INackable. Only distributed queues take them back,
+// since they notify the worker once the jobs are redelivered. The queue is not pulled further once it's reached.
+const delayLookahead = 1000
+
+// delayedItem is a job waiting to be enqueued until it's due
+type delayedItem[T, R any] struct {
+	job   iJob[T, R]
+	dueAt time.Time
+	index int
+}
+
+// delayedHeap implements heap.Interface ordered by the due time of the items
+type delayedHeap[T, R any] []*delayedItem[T, R]
+
+func (h delayedHeap[T, R]) Len() int { return len(h) }
+
+func (h delayedHeap[T, R]) Less(i, j int) bool {
+	if h[i].dueAt.Equal(h[j].dueAt) {
+		// Tie-breaker: lower insertion index => earlier
+		return h[i].index < h[j].index
+	}
+	return h[i].dueAt.Before(h[j].dueAt)
+}
+
+func (h delayedHeap[T, R]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *delayedHeap[T, R]) Push(x any) { *h = append(*h, x.(*delayedItem[T, R])) }
+
+func (h *delayedHeap[T, R]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// delayedJobs holds jobs those are not due yet, using a single timer for the earliest one.
+// When jobs are due, they are handed over to the onDue function to be enqueued.
+// It doesn't hand over any job until Start is called.
+type delayedJobs[T, R any] struct {
+	items          delayedHeap[T, R]
+	insertionCount int
+	timer          *time.Timer
+	stopped        bool
+	len            atomic.Int64
+	onDue          func(j iJob[T, R])
+	mx             sync.Mutex
+}
+
+func newDelayedJobs[T, R any](onDue func(j iJob[T, R])) *delayedJobs[T, R] {
+	return &delayedJobs[T, R]{
+		items:   make(delayedHeap[T, R], 0),
+		onDue:   onDue,
+		stopped: true,
+	}
+}
+
+// Len returns the number of jobs those are waiting to be due,
+// including the due jobs those are being handed over right now.
+func (d *delayedJobs[T, R]) Len() int {
+	return int(d.len.Load())
+}
+
+// Add schedules the job to be handed over at the given time.
+// Time complexity: O(log n)
+func (d *delayedJobs[T, R]) Add(j iJob[T, R], dueAt time.Time) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	heap.Push(&d.items, &delayedItem[T, R]{job: j, dueAt: dueAt, index: d.insertionCount})
+	d.insertionCount++
+	d.len.Add(1)
+
+	// reschedule only if the new item became the earliest one
+	if d.items[0].job == j {
+		d.schedule()
+	}
+}
+
+// Remove removes the given job if it's delayed.
+// Time complexity: O(n)
+func (d *delayedJobs[T, R]) Remove(j iJob[T, R]) bool {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	for i, item := range d.items {
+		if item.job != j {
+			continue
+		}
+
+		heap.Remove(&d.items, i)
+		d.len.Add(-1)
+
+		// reschedule if the earliest item is removed
+		if i == 0 {
+			d.schedule()
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// Values returns all the delayed jobs.
+func (d *delayedJobs[T, R]) Values() []iJob[T, R] {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	values := make([]iJob[T, R], 0, len(d.items))
+	for _, item := range d.items {
+		values = append(values, item.job)
+	}
+
+	return values
+}
+
+// Purge removes all delayed jobs and returns them.
+func (d *delayedJobs[T, R]) Purge() []iJob[T, R] {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	values := make([]iJob[T, R], 0, len(d.items))
+	for _, item := range d.items {
+		values = append(values, item.job)
+	}
+
+	d.items = make(delayedHeap[T, R], 0)
+	d.len.Add(-int64(len(values)))
+	d.stopTimer()
+
+	return values
+}
+
+// Stop stops handing over the due jobs, the delayed jobs are kept until Start is called.
+func (d *delayedJobs[T, R]) Stop() {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.stopped = true
+	d.stopTimer()
+}
+
+// Start starts handing over the due jobs.
+func (d *delayedJobs[T, R]) Start() {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.stopped = false
+	d.schedule()
+}
+
+func (d *delayedJobs[T, R]) stopTimer() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+// schedule (re)arms the timer for the earliest item, must be called with the lock held
+func (d *delayedJobs[T, R]) schedule() {
+	d.stopTimer()
+
+	if d.stopped || len(d.items) == 0 {
+		return
+	}
+
+	d.timer = time.AfterFunc(time.Until(d.items[0].dueAt), d.handOverDueJobs)
+}
+
+// handOverDueJobs pops all the due jobs and hands them over to the onDue function
+func (d *delayedJobs[T, R]) handOverDueJobs() {
+	d.mx.Lock()
+
+	if d.stopped {
+		d.mx.Unlock()
+		return
+	}
+
+	now := time.Now()
+	due := make([]iJob[T, R], 0)
+
+	for len(d.items) > 0 && !d.items[0].dueAt.After(now) {
+		due = append(due, heap.Pop(&d.items).(*delayedItem[T, R]).job)
+	}
+
+	d.schedule()
+	d.mx.Unlock()
+
+	for _, j := range due {
+		d.onDue(j)
+		// decrease the length after handing over to not to be seen as finished in between
+		d.len.Add(-1)
+	}
+}
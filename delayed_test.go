@@ -0,0 +1,237 @@
+package varmq
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelayedJobs(t *testing.T) {
+	t.Run("jobs are handed over in due order", func(t *testing.T) {
+		var mx sync.Mutex
+		handedOver := make([]string, 0)
+		done := make(chan struct{})
+
+		d := newDelayedJobs(func(j iJob[string, int]) {
+			mx.Lock()
+			defer mx.Unlock()
+
+			handedOver = append(handedOver, j.ID())
+			if len(handedOver) == 3 {
+				close(done)
+			}
+		})
+		d.Start()
+
+		now := time.Now()
+		d.Add(newJob[string, int]("c", jobConfigs{Id: "c"}), now.Add(60*time.Millisecond))
+		d.Add(newJob[string, int]("a", jobConfigs{Id: "a"}), now.Add(20*time.Millisecond))
+		d.Add(newJob[string, int]("b", jobConfigs{Id: "b"}), now.Add(40*time.Millisecond))
+
+		assert.Equal(t, 3, d.Len())
+		assert.Len(t, d.Values(), 3)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("delayed jobs should be handed over")
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+		assert.Equal(t, []string{"a", "b", "c"}, handedOver)
+		assert.Equal(t, 0, d.Len())
+	})
+
+	t.Run("stopped jobs are not handed over until started", func(t *testing.T) {
+		handedOver := make(chan string, 1)
+		d := newDelayedJobs(func(j iJob[string, int]) {
+			handedOver <- j.ID()
+		})
+
+		d.Add(newJob[string, int]("a", jobConfigs{Id: "a"}), time.Now())
+
+		select {
+		case <-handedOver:
+			t.Fatal("job should not be handed over before start")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		d.Start()
+		assert.Equal(t, "a", <-handedOver)
+	})
+
+	t.Run("purge removes all jobs", func(t *testing.T) {
+		d := newDelayedJobs(func(j iJob[string, int]) {
+			t.Fatal("purged job should not be handed over")
+		})
+		d.Start()
+
+		d.Add(newJob[string, int]("a", jobConfigs{Id: "a"}), time.Now().Add(20*time.Millisecond))
+		purged := d.Purge()
+
+		assert.Len(t, purged, 1)
+		assert.Equal(t, 0, d.Len())
+		time.Sleep(50 * time.Millisecond)
+	})
+}
+
+func TestDelayedQueueJobs(t *testing.T) {
+	t.Run("WithDelay", func(t *testing.T) {
+		q := NewWorker(func(data int) (time.Time, error) {
+			return time.Now(), nil
+		}).BindQueue()
+		defer q.Close()
+
+		start := time.Now()
+		job, ok := q.Add(1, WithDelay(100*time.Millisecond))
+		assert.True(t, ok)
+		assert.Equal(t, 1, q.NumPending(), "delayed job should be pending")
+		assert.Equal(t, "Queued", job.Status())
+
+		processedAt, err := job.Result()
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, processedAt.Sub(start), 100*time.Millisecond)
+		assert.Equal(t, 0, q.NumPending())
+	})
+
+	t.Run("delay starts once the job is admitted", func(t *testing.T) {
+		w := NewWorker(func(data int) (int, error) {
+			return data, nil
+		}, WithMaxPending(1), WithOverflowPolicy(OverflowBlock))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		first, _ := q.Add(1)
+		opts := []JobConfigFunc{WithDelay(time.Hour)}
+
+		added := make(chan EnqueuedJob[int])
+		go func() {
+			job, _ := q.Add(2, opts...)
+			added <- job
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		admittedAt := time.Now()
+		assert.NoError(t, first.Cancel())
+
+		job := <-added
+		assert.False(t, job.(iJob[int, int]).RunAt().Before(admittedAt.Add(time.Hour)), "the waiting time should not be taken from the delay")
+	})
+
+	t.Run("the last of WithDelay and WithRunAt wins", func(t *testing.T) {
+		runAt := time.Now().Add(time.Minute)
+
+		c := loadJobConfigs(newConfig(), WithDelay(time.Hour), WithRunAt(runAt))
+		assert.Equal(t, runAt, c.RunAt)
+		assert.Zero(t, c.Delay)
+
+		c = loadJobConfigs(newConfig(), WithRunAt(runAt), WithDelay(time.Hour))
+		assert.True(t, c.RunAt.IsZero())
+		assert.Equal(t, time.Hour, c.Delay)
+	})
+
+	t.Run("WithRunAt on priority queue", func(t *testing.T) {
+		processed := make(chan int, 2)
+		q := NewVoidWorker(func(data int) {
+			processed <- data
+		}).BindPriorityQueue()
+		defer q.Close()
+
+		q.Add(1, 1, WithRunAt(time.Now().Add(80*time.Millisecond)))
+		q.Add(2, 2)
+
+		assert.Equal(t, 2, <-processed, "not delayed job should be processed first")
+		assert.Equal(t, 1, <-processed)
+	})
+
+	t.Run("AddAll with delay", func(t *testing.T) {
+		q := NewWorker(func(data int) (int, error) {
+			return data, nil
+		}).BindQueue()
+		defer q.Close()
+
+		group := q.AddAll([]Item[int]{{Value: 1}, {Value: 2}}, WithDelay(50*time.Millisecond))
+		assert.Equal(t, 2, q.NumPending())
+
+		group.Wait()
+		assert.Equal(t, 0, q.NumPending())
+	})
+
+	t.Run("JobById finds delayed job", func(t *testing.T) {
+		q := NewWorker(func(data int) (int, error) {
+			return data, nil
+		}, WithCache(new(sync.Map))).BindQueue()
+		defer q.Close()
+
+		q.Add(1, WithJobId("delayed"), WithDelay(time.Hour))
+
+		job, err := q.JobById("delayed")
+		assert.NoError(t, err)
+		assert.Equal(t, "Queued", job.Status())
+	})
+
+	t.Run("WaitUntilFinished waits for delayed jobs", func(t *testing.T) {
+		processed := make(chan int, 1)
+		q := NewVoidWorker(func(data int) {
+			processed <- data
+		}).BindQueue()
+		defer q.Close()
+
+		q.Add(1, WithDelay(50*time.Millisecond))
+		q.WaitUntilFinished()
+
+		assert.Len(t, processed, 1)
+	})
+
+	t.Run("persisted delayed job is held until it's due", func(t *testing.T) {
+		processed := make(chan int, 1)
+		mq := newMockPersistentQueue()
+
+		// simulate a job persisted by a previous process
+		j := newJob[int, any](1, jobConfigs{Id: "job-1", RunAt: time.Now().Add(80 * time.Millisecond)})
+		j.ChangeStatus(queued)
+		data, err := j.Json()
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"run_at"`)
+		mq.Enqueue(data)
+
+		q := NewVoidWorker(func(data int) {
+			processed <- data
+		}).WithPersistentQueue(mq)
+		defer q.Close()
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, 1, q.NumPending(), "held job should be pending")
+		assert.Equal(t, 1, mq.Unacked(), "held job should not be acknowledged")
+
+		select {
+		case <-processed:
+		case <-time.After(time.Second):
+			t.Fatal("persisted delayed job should be processed once it's due")
+		}
+
+		q.WaitUntilFinished()
+		assert.Equal(t, 0, mq.Unacked())
+	})
+
+	t.Run("removed job is not handed over", func(t *testing.T) {
+		handedOver := make(chan string, 2)
+		d := newDelayedJobs(func(j iJob[string, int]) {
+			handedOver <- j.ID()
+		})
+		d.Start()
+
+		a := newJob[string, int]("a", jobConfigs{Id: "a"})
+		d.Add(a, time.Now().Add(20*time.Millisecond))
+		d.Add(newJob[string, int]("b", jobConfigs{Id: "b"}), time.Now().Add(40*time.Millisecond))
+
+		assert.True(t, d.Remove(a))
+		assert.False(t, d.Remove(a), "job should be removed only once")
+		assert.Equal(t, 1, d.Len())
+		assert.Equal(t, "b", <-handedOver)
+	})
+}
@@ -0,0 +1,307 @@
+package varmq
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/goptics/varmq/utils"
+)
+
+// dependencyHistory is the number of the finished jobs those outcome is remembered,
+// so a job depending on an already finished one still knows whether it's succeeded.
+const dependencyHistory = 10000
+
+// ErrParentFailed is the error of a job those has been cancelled since one of its parents failed.
+var ErrParentFailed = errors.New("parent job failed")
+
+// ErrPersistentDependency is the error of a job those depends on others, but is added into a persistent or distributed queue.
+// The held jobs and the outcomes of the finished ones are kept in memory by the worker,
+// so a job waiting for a parent finished before a restart, or by another process, would wait forever.
+var ErrPersistentDependency = errors.New("dependencies are not supported by persistent and distributed queues")
+
+// ParentFailurePolicy decides what happens to a job when one of the jobs it depends on fails.
+type ParentFailurePolicy uint8
+
+const (
+	// CancelOnParentFailure cancels the job with ErrParentFailed, which cancels its own dependents in turn.
+	CancelOnParentFailure ParentFailurePolicy = iota
+	// RunOnParentFailure runs the job anyway once all of its parents are finished.
+	RunOnParentFailure
+	// CompensateOnParentFailure cancels the job like CancelOnParentFailure, and runs its compensation instead,
+	// see WithCompensation.
+	CompensateOnParentFailure
+)
+
+// dependency is what a job depends on, and what happens to it when a parent fails
+type dependency struct {
+	Parents    []string
+	OnFailure  ParentFailurePolicy
+	Compensate func()
+}
+
+// WithDependsOn makes the job runnable only after the jobs with the given ids succeed.
+// The job is held by the worker until then, and it's cancelled with ErrParentFailed if one of them fails,
+// unless another policy is set by WithParentFailurePolicy. A parent those is not added yet is waited for.
+// It's supported only by in-memory queues, persistent and distributed queues reject the job with ErrPersistentDependency.
+func WithDependsOn(ids ...string) JobConfigFunc {
+	return func(c *jobConfigs) {
+		// clipped to not to share the parents of the worker defaults between the jobs
+		c.Dependency.Parents = append(slices.Clip(c.Dependency.Parents), ids...)
+	}
+}
+
+// WithParentFailurePolicy sets what happens to the job when one of the jobs it depends on fails,
+// default is CancelOnParentFailure.
+func WithParentFailurePolicy(policy ParentFailurePolicy) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.Dependency.OnFailure = policy
+	}
+}
+
+// WithCompensation sets the function to be called instead of running the job when one of its parents fails,
+// e.g. to add a job those reverts the work of the succeeded parents. It implies CompensateOnParentFailure.
+func WithCompensation(fn func()) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.Dependency.OnFailure = CompensateOnParentFailure
+		c.Dependency.Compensate = fn
+	}
+}
+
+// dependent is a job held until its parents are finished
+type dependent[T, R any] struct {
+	job     iJob[T, R]
+	waiting int
+	// failed is the error of the first failed parent
+	failed error
+}
+
+// dependencies holds the jobs waiting for their parents, and remembers the outcome of the finished jobs
+type dependencies[T, R any] struct {
+	held     map[iJob[T, R]]*dependent[T, R]
+	waiters  map[string][]*dependent[T, R]
+	outcomes map[string]error
+	// finished is the ids of the remembered outcomes in the order they're finished, to forget the oldest ones
+	finished []string
+	mx       sync.Mutex
+}
+
+func newDependencies[T, R any]() *dependencies[T, R] {
+	return &dependencies[T, R]{
+		held:     make(map[iJob[T, R]]*dependent[T, R]),
+		waiters:  make(map[string][]*dependent[T, R]),
+		outcomes: make(map[string]error),
+	}
+}
+
+// add holds the job until its unfinished parents are finished, and returns false if none of them is unfinished.
+// The error of the first failed parent is returned if the job isn't held.
+func (d *dependencies[T, R]) add(j iJob[T, R]) (bool, error) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	dep := &dependent[T, R]{job: j}
+
+	for _, id := range j.Dependency().Parents {
+		err, ok := d.outcomes[id]
+
+		if !ok {
+			dep.waiting++
+			d.waiters[id] = append(d.waiters[id], dep)
+			continue
+		}
+
+		if err != nil && dep.failed == nil {
+			dep.failed = fmt.Errorf("%w: %s: %w", ErrParentFailed, id, err)
+		}
+	}
+
+	if dep.waiting == 0 || (dep.failed != nil && j.Dependency().OnFailure != RunOnParentFailure) {
+		d.unwait(dep)
+		return false, dep.failed
+	}
+
+	d.held[j] = dep
+	return true, nil
+}
+
+// finish remembers the outcome of the finished job, and returns the dependents those are not held anymore
+func (d *dependencies[T, R]) finish(id string, err error) []*dependent[T, R] {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if _, ok := d.outcomes[id]; !ok {
+		d.finished = append(d.finished, id)
+	}
+
+	d.outcomes[id] = err
+
+	if len(d.finished) > dependencyHistory {
+		delete(d.outcomes, d.finished[0])
+		d.finished = d.finished[1:]
+	}
+
+	waiters := d.waiters[id]
+	delete(d.waiters, id)
+
+	var released []*dependent[T, R]
+
+	for _, dep := range waiters {
+		if _, ok := d.held[dep.job]; !ok {
+			continue
+		}
+
+		dep.waiting--
+
+		if err != nil && dep.failed == nil {
+			dep.failed = fmt.Errorf("%w: %s: %w", ErrParentFailed, id, err)
+		}
+
+		// a failed job doesn't wait for the other parents unless it runs anyway
+		if dep.waiting == 0 || (dep.failed != nil && dep.job.Dependency().OnFailure != RunOnParentFailure) {
+			d.release(dep)
+			released = append(released, dep)
+		}
+	}
+
+	return released
+}
+
+// release stops holding the dependent, it must be called with the lock held
+func (d *dependencies[T, R]) release(dep *dependent[T, R]) {
+	delete(d.held, dep.job)
+	d.unwait(dep)
+}
+
+// unwait removes the dependent from the waiters of its parents, it must be called with the lock held
+func (d *dependencies[T, R]) unwait(dep *dependent[T, R]) {
+	for _, id := range dep.job.Dependency().Parents {
+		waiters := d.waiters[id]
+
+		for i, w := range waiters {
+			if w == dep {
+				waiters = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+
+		if len(waiters) == 0 {
+			delete(d.waiters, id)
+		} else {
+			d.waiters[id] = waiters
+		}
+	}
+}
+
+// remove stops holding the given job if it's held
+func (d *dependencies[T, R]) remove(j iJob[T, R]) bool {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	dep, ok := d.held[j]
+	if ok {
+		d.release(dep)
+	}
+
+	return ok
+}
+
+// Len returns the number of the held jobs
+func (d *dependencies[T, R]) Len() int {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	return len(d.held)
+}
+
+// Values returns the held jobs
+func (d *dependencies[T, R]) Values() []iJob[T, R] {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	values := make([]iJob[T, R], 0, len(d.held))
+	for j := range d.held {
+		values = append(values, j)
+	}
+
+	return values
+}
+
+// Purge stops holding all the jobs and returns them
+func (d *dependencies[T, R]) Purge() []iJob[T, R] {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	values := make([]iJob[T, R], 0, len(d.held))
+	for j := range d.held {
+		values = append(values, j)
+	}
+
+	clear(d.held)
+	clear(d.waiters)
+
+	return values
+}
+
+// checkDependency returns ErrPersistentDependency if the job depends on others, but the queue of the worker is persistent
+func (w *worker[T, R]) checkDependency(j iJob[T, R]) error {
+	if _, ok := w.Queue.(IAcknowledgeable); ok && len(j.Dependency().Parents) > 0 {
+		return ErrPersistentDependency
+	}
+
+	return nil
+}
+
+// holdJob holds the job until its parents are finished, and returns true if it's held or finished by a failed parent.
+// It's called before enqueueing the job, so the job is marked as queued to be cancellable while it's held.
+func (w *worker[T, R]) holdJob(j iJob[T, R]) bool {
+	if len(j.Dependency().Parents) == 0 {
+		return false
+	}
+
+	j.ChangeStatusFrom(created, queued)
+	w.cacheJob(j)
+
+	held, failed := w.deps.add(j)
+	if held {
+		return true
+	}
+
+	if failed == nil || j.Dependency().OnFailure == RunOnParentFailure {
+		return false
+	}
+
+	w.failDependent(j, failed)
+	return true
+}
+
+// finishDependency remembers the outcome of the finished job, and runs or fails its released dependents
+func (w *worker[T, R]) finishDependency(j iJob[T, R], err error) {
+	id := j.ID()
+	if id == "" {
+		return
+	}
+
+	for _, dep := range w.deps.finish(id, err) {
+		if dep.failed == nil || dep.job.Dependency().OnFailure == RunOnParentFailure {
+			w.enqueueDelayedJob(dep.job)
+			continue
+		}
+
+		w.failDependent(dep.job, dep.failed)
+	}
+}
+
+// failDependent cancels the job since its parent failed, and runs its compensation if any
+func (w *worker[T, R]) failDependent(j iJob[T, R], err error) {
+	// the job might be cancelled in the meantime
+	if j.abort(err, true) != nil {
+		return
+	}
+
+	if fn := j.Dependency().Compensate; fn != nil && j.Dependency().OnFailure == CompensateOnParentFailure {
+		// the compensation might add a job those waits for room in the queue, so it must not block the caller
+		go utils.WithSafe("compensation", fn)
+	}
+}
@@ -0,0 +1,176 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencies(t *testing.T) {
+	failure := errors.New("failed")
+
+	newRecorder := func() (func(string) (string, error), func() []string) {
+		var mx sync.Mutex
+		order := make([]string, 0)
+
+		return func(data string) (string, error) {
+				mx.Lock()
+				defer mx.Unlock()
+
+				if data == "fail" {
+					return "", failure
+				}
+
+				order = append(order, data)
+				return data, nil
+			}, func() []string {
+				mx.Lock()
+				defer mx.Unlock()
+
+				return append([]string(nil), order...)
+			}
+	}
+
+	t.Run("runs the job once its parents succeed", func(t *testing.T) {
+		fn, order := newRecorder()
+		q := NewWorker(fn, WithConcurrency(4)).BindQueue()
+		defer q.Close()
+
+		child, _ := q.Add("child", WithDependsOn("a", "b"))
+		assert.Equal(t, 1, q.NumPending(), "the held job should be counted as pending")
+
+		q.Add("a", WithJobId("a"))
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, "Queued", child.Status(), "the job should wait for all of its parents")
+
+		q.Add("b", WithJobId("b"))
+
+		result, err := child.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, "child", result)
+		assert.Equal(t, "child", order()[2])
+	})
+
+	t.Run("cancels the dependents of a failed job", func(t *testing.T) {
+		fn, order := newRecorder()
+		q := NewWorker(fn).BindQueue()
+		defer q.Close()
+
+		child, _ := q.Add("child", WithJobId("child"), WithDependsOn("parent"))
+		grandchild, _ := q.Add("grandchild", WithDependsOn("child"))
+		q.Add("fail", WithJobId("parent"))
+
+		_, err := child.Result()
+		assert.ErrorIs(t, err, ErrParentFailed)
+		assert.ErrorIs(t, err, failure)
+
+		_, err = grandchild.Result()
+		assert.ErrorIs(t, err, ErrParentFailed, "the cancellation should be cascaded")
+
+		q.WaitUntilFinished()
+		assert.Empty(t, order())
+	})
+
+	t.Run("runs the dependent anyway by the policy", func(t *testing.T) {
+		fn, _ := newRecorder()
+		q := NewWorker(fn).BindQueue()
+		defer q.Close()
+
+		child, _ := q.Add("child", WithDependsOn("parent"), WithParentFailurePolicy(RunOnParentFailure))
+		q.Add("fail", WithJobId("parent"))
+
+		result, err := child.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, "child", result)
+	})
+
+	t.Run("remembers the outcome of the finished parents", func(t *testing.T) {
+		fn, _ := newRecorder()
+		q := NewWorker(fn).BindQueue()
+		defer q.Close()
+
+		succeeded, _ := q.Add("ok", WithJobId("succeeded"))
+		failed, _ := q.Add("fail", WithJobId("failed"))
+		succeeded.Result()
+		failed.Result()
+
+		child, _ := q.Add("child", WithDependsOn("succeeded"))
+		result, err := child.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, "child", result)
+
+		child, ok := q.Add("child", WithDependsOn("succeeded", "failed"))
+		assert.True(t, ok)
+		_, err = child.Result()
+		assert.ErrorIs(t, err, ErrParentFailed)
+	})
+
+	t.Run("compensates the failure", func(t *testing.T) {
+		fn, _ := newRecorder()
+		q := NewWorker(fn).BindQueue()
+		defer q.Close()
+
+		compensated := make(chan struct{})
+		child, _ := q.Add("child", WithDependsOn("parent"), WithCompensation(func() {
+			close(compensated)
+		}))
+		q.Add("fail", WithJobId("parent"))
+
+		_, err := child.Result()
+		assert.ErrorIs(t, err, ErrParentFailed)
+
+		select {
+		case <-compensated:
+		case <-time.After(time.Second):
+			t.Fatal("the compensation should be called")
+		}
+	})
+
+	t.Run("cancels and purges the held jobs", func(t *testing.T) {
+		fn, _ := newRecorder()
+		q := NewWorker(fn).BindQueue()
+		defer q.Close()
+
+		cancelled, _ := q.Add("cancelled", WithJobId("cancelled"), WithDependsOn("parent"))
+		dependent, _ := q.Add("dependent", WithDependsOn("cancelled"))
+		purged, _ := q.Add("purged", WithDependsOn("parent"))
+		assert.Equal(t, 3, q.NumPending())
+
+		assert.NoError(t, cancelled.Cancel())
+		_, err := cancelled.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+
+		_, err = dependent.Result()
+		assert.ErrorIs(t, err, ErrParentFailed)
+		assert.ErrorIs(t, err, ErrJobCancelled)
+		assert.Equal(t, 1, q.NumPending())
+
+		q.Purge()
+		assert.Zero(t, q.NumPending())
+		assert.Equal(t, "Closed", purged.Status())
+	})
+	t.Run("persistent and distributed queues reject the dependent jobs", func(t *testing.T) {
+		fn, _ := newRecorder()
+		mq := newMockPersistentQueue()
+		q := NewWorker(fn).WithPersistentQueue(mq)
+		defer q.Close()
+
+		job, ok := q.Add("dependent", WithJobId("dependent"), WithDependsOn("parent"))
+		assert.False(t, ok)
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrPersistentDependency)
+		assert.Zero(t, mq.Len(), "the job should not be enqueued")
+
+		_, err = q.AddWait(context.Background(), "dependent", WithJobId("dependent"), WithDependsOn("parent"))
+		assert.ErrorIs(t, err, ErrPersistentDependency)
+
+		dq := newMockDistributedQueue()
+		producer := NewDistributedQueue[string, string](dq)
+		assert.False(t, producer.Add("dependent", WithJobId("dependent"), WithDependsOn("parent")))
+		assert.Zero(t, dq.Len(), "the job should not be enqueued")
+	})
+}
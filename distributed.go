@@ -1,19 +1,93 @@
 package varmq
 
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// errNoReplyQueue is the error of AddAndWait of a distributed queue those has no reply queue
+var errNoReplyQueue = errors.New("reply queue is required to wait for the result, see WithReplyQueue")
+
 type DistributedQueue[T, R any] interface {
 	IExternalBaseQueue
 	// Time complexity: O(1)
 	Add(data T, configs ...JobConfigFunc) bool
+	// AddAndWait adds a job and blocks until its result is replied by the consumer, see WithReplyQueue and WithReplyQueues.
+	// The job id is the correlation id of the reply, a unique one is generated if it's not given.
+	// It returns the context error if the context is done before the reply arrives.
+	AddAndWait(ctx context.Context, data T, configs ...JobConfigFunc) (R, error)
+}
+
+type DistributedConfigFunc func(*distributedConfigs)
+
+type distributedConfigs struct {
+	ReplyAddress string
+	ReplyQueue   IDistributedQueue
+	Codec        Codec
+}
+
+// WithReplyQueue sets the queue those the consumers reply the results of the jobs added by AddAndWait into.
+// The address is passed to the consumers along with the jobs to open the same queue, see WithReplyQueues.
+// Every producer must have its own reply queue, since the replies are routed only to the waiting calls of the producer.
+func WithReplyQueue(address string, q IDistributedQueue) DistributedConfigFunc {
+	return func(c *distributedConfigs) {
+		c.ReplyAddress = address
+		c.ReplyQueue = q
+	}
 }
 
 type distributedQueue[T, R any] struct {
 	internalQueue IDistributedQueue
+	configs       distributedConfigs
+	waiters       replyWaiters[R]
 }
 
-func NewDistributedQueue[T, R any](internalQueue IDistributedQueue) DistributedQueue[T, R] {
-	return &distributedQueue[T, R]{
+func NewDistributedQueue[T, R any](internalQueue IDistributedQueue, configs ...DistributedConfigFunc) DistributedQueue[T, R] {
+	q := &distributedQueue[T, R]{
 		internalQueue: internalQueue,
 	}
+
+	for _, config := range configs {
+		config(&q.configs)
+	}
+
+	if replies := q.configs.ReplyQueue; replies != nil {
+		replies.Subscribe(q.handleReplySubscription)
+		// the replies enqueued before subscribing are received as well
+		q.receiveReplies()
+	}
+
+	return q
+}
+
+// handleReplySubscription receives the replies once they're enqueued into the reply queue
+func (q *distributedQueue[T, R]) handleReplySubscription(action string) {
+	switch action {
+	case "enqueued":
+		q.receiveReplies()
+	}
+}
+
+// receiveReplies drains the reply queue and delivers the replies to the waiting calls
+func (q *distributedQueue[T, R]) receiveReplies() {
+	replies := q.configs.ReplyQueue
+
+	for {
+		v, ok, ackId := replies.DequeueWithAckId()
+		if !ok {
+			return
+		}
+
+		if data, ok := v.([]byte); ok {
+			if r, err := parseStoredResult[R](data, q.configs.Codec); err == nil {
+				q.waiters.deliver(r)
+			}
+		}
+
+		replies.Acknowledge(ackId)
+	}
 }
 
 func (q *distributedQueue[T, R]) NumPending() int {
@@ -21,9 +95,19 @@ func (q *distributedQueue[T, R]) NumPending() int {
 }
 
 func (q *distributedQueue[T, R]) Add(data T, c ...JobConfigFunc) bool {
-	j := newVoidJob[T, R](data, withRequiredJobId(loadJobConfigs(newConfig(), c...)))
+	return q.add(data, withRequiredJobId(loadJobConfigs(newConfig(), c...)))
+}
+
+func (q *distributedQueue[T, R]) add(data T, c jobConfigs) bool {
+	// the consumers can't hold the job, see ErrPersistentDependency
+	if len(c.Dependency.Parents) > 0 {
+		return false
+	}
 
-	jBytes, err := j.Json()
+	j := newVoidJob[T, R](data, c)
+	j.admit()
+
+	jBytes, err := j.encode(q.configs.Codec)
 
 	if err != nil {
 		j.close()
@@ -41,6 +125,41 @@ func (q *distributedQueue[T, R]) Add(data T, c ...JobConfigFunc) bool {
 	return true
 }
 
+func (q *distributedQueue[T, R]) AddAndWait(ctx context.Context, data T, c ...JobConfigFunc) (R, error) {
+	var zero R
+
+	if q.configs.ReplyQueue == nil {
+		return zero, errNoReplyQueue
+	}
+
+	jc := loadJobConfigs(newConfig(), append(c, withReplyTo(q.configs.ReplyAddress))...)
+	if len(jc.Dependency.Parents) > 0 {
+		return zero, ErrPersistentDependency
+	}
+
+	if jc.Id == "" {
+		jc.Id = rand.Text()
+	}
+
+	reply, ok := q.waiters.wait(jc.Id)
+	if !ok {
+		return zero, fmt.Errorf("job %s is already waiting for its reply", jc.Id)
+	}
+
+	if !q.add(data, jc) {
+		q.waiters.forget(jc.Id)
+		return zero, errors.New("failed to add job")
+	}
+
+	select {
+	case r := <-reply:
+		return r.Data, r.Err
+	case <-ctx.Done():
+		q.waiters.forget(jc.Id)
+		return zero, ctx.Err()
+	}
+}
+
 func (q *distributedQueue[T, R]) Purge() {
 	q.internalQueue.Purge()
 }
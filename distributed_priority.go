@@ -8,12 +8,21 @@ type DistributedPriorityQueue[T, R any] interface {
 
 type distributedPriorityQueue[T, R any] struct {
 	internalQueue IDistributedPriorityQueue
+	configs       distributedConfigs
 }
 
-func NewDistributedPriorityQueue[T, R any](internalQueue IDistributedPriorityQueue) DistributedPriorityQueue[T, R] {
-	return &distributedPriorityQueue[T, R]{
+// NewDistributedPriorityQueue creates a producer of the given queue, only the codec of the configs is used by it,
+// see WithDistributedCodec.
+func NewDistributedPriorityQueue[T, R any](internalQueue IDistributedPriorityQueue, configs ...DistributedConfigFunc) DistributedPriorityQueue[T, R] {
+	q := &distributedPriorityQueue[T, R]{
 		internalQueue: internalQueue,
 	}
+
+	for _, config := range configs {
+		config(&q.configs)
+	}
+
+	return q
 }
 
 func (q *distributedPriorityQueue[T, R]) NumPending() int {
@@ -21,9 +30,18 @@ func (q *distributedPriorityQueue[T, R]) NumPending() int {
 }
 
 func (q *distributedPriorityQueue[T, R]) Add(data T, priority int, c ...JobConfigFunc) bool {
-	j := newVoidJob[T, R](data, withRequiredJobId(loadJobConfigs(newConfig(), c...)))
+	jc := withRequiredJobId(loadJobConfigs(newConfig(), c...))
+
+	// the consumers can't hold the job, see ErrPersistentDependency
+	if len(jc.Dependency.Parents) > 0 {
+		return false
+	}
+
+	j := newVoidJob[T, R](data, jc)
+	j.SetPriority(priority)
+	j.admit()
 
-	jBytes, err := j.Json()
+	jBytes, err := j.encode(q.configs.Codec)
 
 	if err != nil {
 		j.close()
@@ -1,5 +1,20 @@
 package varmq
 
+import "errors"
+
+// ErrJobTimeout is the error of a job those exceeded its execution timeout set by WithJobTimeout.
+var ErrJobTimeout = errors.New("job timed out")
+
+// ErrJobCancelled is the error of a job those has been cancelled before it's finished.
+var ErrJobCancelled = errors.New("job cancelled")
+
+// ErrQueueFull is the error of a job those couldn't be added into a full queue,
+// or has been dropped to make room for a new one, see WithMaxPending.
+var ErrQueueFull = errors.New("queue is full")
+
+// ErrJobPurged is the error of a pending job those has been removed by Purge or Close before it's processed.
+var ErrJobPurged = errors.New("job is purged")
+
 func selectError(errs ...error) error {
 	for _, err := range errs {
 		if err != nil {
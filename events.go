@@ -0,0 +1,217 @@
+package varmq
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goptics/varmq/utils"
+)
+
+// EventType is the kind of a job lifecycle event.
+type EventType uint8
+
+const (
+	// EventEnqueued is emitted when a job is accepted by the queue, delayed jobs included.
+	// It's emitted before any other event of the job, and never for a job those is rejected.
+	EventEnqueued EventType = iota
+	// EventStarted is emitted when an attempt of a job is started.
+	EventStarted
+	// EventSucceeded is emitted when an attempt of a job is succeeded.
+	EventSucceeded
+	// EventFailed is emitted when an attempt of a job is failed with an error.
+	EventFailed
+	// EventPanicked is emitted when the worker function panicked during an attempt of a job.
+	EventPanicked
+	// EventRetrying is emitted when a failed job is scheduled to be retried.
+	EventRetrying
+	// EventClosed is emitted when a job is done and its resources are freed.
+	EventClosed
+
+	numEventTypes
+)
+
+// eventsBufferSize is the buffer size of the channel returned by Events.
+const eventsBufferSize = 256
+
+func (t EventType) String() string {
+	switch t {
+	case EventEnqueued:
+		return "Enqueued"
+	case EventStarted:
+		return "Started"
+	case EventSucceeded:
+		return "Succeeded"
+	case EventFailed:
+		return "Failed"
+	case EventPanicked:
+		return "Panicked"
+	case EventRetrying:
+		return "Retrying"
+	case EventClosed:
+		return "Closed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event describes a transition in the lifecycle of a job.
+type Event struct {
+	Type  EventType
+	JobId string
+	// From and To are the statuses of the job before and after the transition, e.g. "Queued" and "Processing".
+	From string
+	To   string
+	// Attempt is the number of the attempts of the job those are started so far.
+	Attempt int
+	// Duration is the waiting time in the queue for the Started event, the running time of the attempt
+	// for the Succeeded, Failed and Panicked events, the backoff delay for the Retrying event,
+	// and the lifetime of the job for the Closed event.
+	Duration time.Duration
+	// Err is the error of the attempt, or the final error of the job for the Closed event.
+	Err  error
+	Time time.Time
+}
+
+// eventHooks holds the hooks and the event stream of a worker
+type eventHooks struct {
+	mx     sync.RWMutex
+	hooks  [numEventTypes][]func(Event)
+	events chan Event
+	// active is set once anybody listens, so the events are not built for nothing
+	active atomic.Bool
+}
+
+func (h *eventHooks) on(t EventType, fn func(Event)) {
+	if fn == nil {
+		return
+	}
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.hooks[t] = append(h.hooks[t], fn)
+	h.active.Store(true)
+}
+
+func (h *eventHooks) stream() <-chan Event {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if h.events == nil {
+		h.events = make(chan Event, eventsBufferSize)
+		h.active.Store(true)
+	}
+
+	return h.events
+}
+
+// emit calls the hooks of the event synchronously and sends the event to the stream without blocking.
+// A panicking hook doesn't affect the job or the other hooks.
+func (h *eventHooks) emit(e Event) {
+	h.mx.RLock()
+	hooks := h.hooks[e.Type]
+	events := h.events
+	h.mx.RUnlock()
+
+	for _, fn := range hooks {
+		utils.WithSafe("event hook", func() {
+			fn(e)
+		})
+	}
+
+	if events == nil {
+		return
+	}
+
+	select {
+	case events <- e:
+	default:
+		// drop the event if the receiver is too slow
+	}
+}
+
+func (w *worker[T, R]) OnEnqueue(fn func(Event)) {
+	w.hooks.on(EventEnqueued, fn)
+}
+
+func (w *worker[T, R]) OnStart(fn func(Event)) {
+	w.hooks.on(EventStarted, fn)
+}
+
+func (w *worker[T, R]) OnSuccess(fn func(Event)) {
+	w.hooks.on(EventSucceeded, fn)
+}
+
+func (w *worker[T, R]) OnError(fn func(Event)) {
+	w.hooks.on(EventFailed, fn)
+}
+
+func (w *worker[T, R]) OnPanic(fn func(Event)) {
+	w.hooks.on(EventPanicked, fn)
+}
+
+func (w *worker[T, R]) OnRetry(fn func(Event)) {
+	w.hooks.on(EventRetrying, fn)
+}
+
+func (w *worker[T, R]) OnClose(fn func(Event)) {
+	w.hooks.on(EventClosed, fn)
+}
+
+func (w *worker[T, R]) Events() <-chan Event {
+	return w.hooks.stream()
+}
+
+// emit emits the event of the job moving from a status to another one
+func (w *worker[T, R]) emit(t EventType, j iJob[T, R], from, to status, d time.Duration, err error) {
+	w.metrics.observe(t, d)
+
+	if !w.hooks.active.Load() {
+		return
+	}
+
+	w.hooks.emit(Event{
+		Type:     t,
+		JobId:    j.ID(),
+		From:     statusName(from),
+		To:       statusName(to),
+		Attempt:  j.Attempts(),
+		Duration: d,
+		Err:      err,
+		Time:     time.Now(),
+	})
+}
+
+// emitEnqueued emits the enqueued event of the job, only once and before any other event of the job
+func (w *worker[T, R]) emitEnqueued(j iJob[T, R]) {
+	if j.isAnnounced() {
+		return
+	}
+
+	j.announce(func() {
+		w.emit(EventEnqueued, j, created, queued, 0, nil)
+	})
+}
+
+// closeJob finishes and closes the job those is done with the given error, and emits the closed event
+func (w *worker[T, R]) closeJob(j iJob[T, R], err error) {
+	j.ChangeStatus(finished)
+	// the delivery is acknowledged by closing the job
+	defer w.leases.release(j.AckId())
+
+	// the key held by a retried job must be freed, e.g. it's cancelled while waiting to be retried
+	if w.keys.close(j) {
+		w.notifySpace()
+		w.notifyToPullNextJobs()
+	}
+
+	if j.close() == nil {
+		// the job might be closed before its adder announces it, e.g. it's cancelled right away
+		w.emitEnqueued(j)
+		w.storeResult(j)
+		w.reply(j)
+		w.emit(EventClosed, j, finished, closed, w.since(j.CreatedAt()), err)
+		w.finishDependency(j, err)
+	}
+}
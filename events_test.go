@@ -0,0 +1,184 @@
+package varmq
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	receive := func(t *testing.T, events <-chan Event) Event {
+		t.Helper()
+
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("event should be emitted")
+			return Event{}
+		}
+	}
+
+	t.Run("emits the lifecycle of a succeeded job", func(t *testing.T) {
+		w := NewWorker(func(data int) (int, error) {
+			return data * 2, nil
+		})
+		events := w.Events()
+		q := w.BindQueue()
+		defer q.Close()
+
+		q.Add(1, WithJobId("job-1"))
+
+		expected := []struct {
+			typ      EventType
+			from, to string
+		}{
+			{EventEnqueued, "Created", "Queued"},
+			{EventStarted, "Queued", "Processing"},
+			{EventSucceeded, "Processing", "Finished"},
+			{EventClosed, "Finished", "Closed"},
+		}
+
+		for _, exp := range expected {
+			e := receive(t, events)
+			assert.Equal(t, exp.typ, e.Type, "expected %s, got %s", exp.typ, e.Type)
+			assert.Equal(t, "job-1", e.JobId)
+			assert.Equal(t, exp.from, e.From)
+			assert.Equal(t, exp.to, e.To)
+			assert.NoError(t, e.Err)
+			assert.False(t, e.Time.IsZero())
+		}
+	})
+
+	t.Run("calls the hooks of failed and retried jobs", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		w := NewErrWorker(func(_ int) error {
+			return errFailed
+		}, WithRetry(2, ConstantBackoff(0)))
+
+		var failed, retried []Event
+		closed := make(chan Event, 1)
+
+		w.OnError(func(e Event) { failed = append(failed, e) })
+		w.OnRetry(func(e Event) { retried = append(retried, e) })
+		w.OnClose(func(e Event) { closed <- e })
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		q.Add(1)
+
+		e := receive(t, closed)
+		assert.ErrorIs(t, e.Err, errFailed)
+		assert.Equal(t, 2, e.Attempt)
+
+		assert.Len(t, failed, 2)
+		assert.Equal(t, "Queued", failed[0].To, "the first attempt is retried")
+		assert.Equal(t, "Finished", failed[1].To)
+		assert.ErrorIs(t, failed[1].Err, errFailed)
+
+		assert.Len(t, retried, 1)
+		assert.Equal(t, 1, retried[0].Attempt)
+	})
+
+	t.Run("calls the panic hook and survives a panicking hook", func(t *testing.T) {
+		w := NewVoidWorker(func(_ int) {
+			panic("boom")
+		})
+
+		panicked := make(chan Event, 1)
+		w.OnStart(func(Event) { panic("hook") })
+		w.OnError(func(Event) { t.Error("panics should not be reported as errors") })
+		w.OnPanic(func(e Event) { panicked <- e })
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1)
+
+		e := receive(t, panicked)
+		assert.Equal(t, EventPanicked, e.Type)
+		assert.ErrorContains(t, e.Err, "boom")
+
+		_, err := job.Result()
+		assert.ErrorContains(t, err, "boom")
+	})
+
+	t.Run("emits the closed event of rejected jobs", func(t *testing.T) {
+		w := NewWorker(func(data int) (int, error) {
+			return data, nil
+		}, WithMaxPending(1))
+
+		closed := make(chan Event, 1)
+		w.OnClose(func(e Event) { closed <- e })
+
+		var enqueued []string
+		w.OnEnqueue(func(e Event) { enqueued = append(enqueued, e.JobId) })
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		_, ok := q.Add(1, WithDelay(time.Hour), WithJobId("job-1"))
+		assert.True(t, ok)
+
+		_, ok = q.Add(2, WithJobId("job-2"))
+		assert.False(t, ok)
+
+		e := receive(t, closed)
+		assert.ErrorIs(t, e.Err, ErrQueueFull)
+		assert.Equal(t, []string{"job-1"}, enqueued, "the rejected job should not be announced as enqueued")
+	})
+
+	t.Run("emits the enqueued event before the others", func(t *testing.T) {
+		w := NewVoidWorker(func(_ int) {}, WithConcurrency(4))
+		events := w.Events()
+		q := w.BindQueue()
+		defer q.Close()
+
+		for i := range 50 {
+			q.Add(i, WithJobId(strconv.Itoa(i)))
+		}
+		q.WaitUntilFinished()
+
+		seen := make(map[string]bool)
+		for range 50 * 4 {
+			e := receive(t, events)
+			if e.Type == EventEnqueued {
+				seen[e.JobId] = true
+			} else {
+				assert.True(t, seen[e.JobId], "%s of job %s is emitted before it's enqueued", e.Type, e.JobId)
+			}
+		}
+	})
+
+	t.Run("drops the events of a slow receiver", func(t *testing.T) {
+		w := NewVoidWorker(func(_ int) {})
+
+		closed := make(chan struct{}, eventsBufferSize)
+		w.OnClose(func(Event) { closed <- struct{}{} })
+		events := w.Events()
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		for i := range eventsBufferSize {
+			q.Add(i)
+		}
+
+		for range eventsBufferSize {
+			<-closed
+		}
+
+		assert.Equal(t, eventsBufferSize, len(events), "the worker should not be blocked by a full stream")
+		assert.Equal(t, events, w.Events(), "the same stream should be returned")
+	})
+
+	t.Run("event type names", func(t *testing.T) {
+		assert.Equal(t, "Enqueued", EventEnqueued.String())
+		assert.Equal(t, "Retrying", EventRetrying.String())
+		assert.Equal(t, "Unknown", numEventTypes.String())
+	})
+}
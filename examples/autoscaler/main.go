@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"github.com/goptics/varmq"
+)
+
+func main() {
+	w := varmq.NewVoidWorker(func(data int) {
+		randomDuration := time.Duration(rand.Intn(1001)+500) * time.Millisecond // Random between 500-1500ms
+		time.Sleep(randomDuration)
+	},
+		varmq.WithAutoscaler(&varmq.AIMDPolicy{Min: 10, Max: 100, Increase: 10}),
+		varmq.WithIdleWorkerExpiryDuration(2*time.Second),
+	)
+
+	q := w.BindQueue()
+	ticker := time.NewTicker(1 * time.Second)
+
+	go func() {
+		for range ticker.C {
+			fmt.Printf("Total Goroutines: %d, Idle Workers: %d\nConcurrency: %d, Processing: %d\nPending Jobs: %d\n\n", runtime.NumGoroutine(), w.NumIdleWorkers(), w.NumConcurrency(), w.NumProcessing(), q.NumPending())
+		}
+	}()
+
+retry:
+	for i := range 1000 {
+		q.Add(i)
+	}
+
+	fmt.Println("Added jobs")
+	q.WaitUntilFinished()
+	time.Sleep(5 * time.Second)
+	goto retry
+}
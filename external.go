@@ -37,6 +37,15 @@ type IExternalQueue[T, R any] interface {
 	// WaitAndClose waits until all pending Jobs in the queue are processed and then closes the queue.
 	// Time complexity: O(n) where n is the number of pending Jobs
 	WaitAndClose() error
+	// CancelJob cancels the job with the given id, it works the same as calling Cancel on the job.
+	// A member of a group is cancelled alone, use Cancel on the group to cancel the whole group.
+	// It requires a cache, see WithCache.
+	CancelJob(id string) error
+	// ReplayDeadLetters enqueues the dead letters matching the filter (all of them if the filter is nil)
+	// as new jobs into the queue, and returns the number of replayed jobs.
+	// It returns an error if the worker has no dead letter queue configured.
+	// Time complexity: O(n) where n is the number of dead letters
+	ReplayDeadLetters(filter func(DeadLetter[T]) bool) (int, error)
 }
 
 func newExternalQueue[T, R any](worker *worker[T, R]) *externalQueue[T, R] {
@@ -47,15 +56,14 @@ func newExternalQueue[T, R any](worker *worker[T, R]) *externalQueue[T, R] {
 
 func (eq *externalQueue[T, R]) postEnqueue(j iJob[T, R]) {
 	defer eq.notifyToPullNextJobs()
-	j.ChangeStatus(queued)
 
-	if id := j.ID(); id != "" {
-		eq.Cache.Store(id, j)
-	}
+	// the job might be picked by the worker already
+	j.ChangeStatusFrom(created, queued)
+	eq.cacheJob(j)
 }
 
 func (eq *externalQueue[T, R]) NumPending() int {
-	return eq.Queue.Len()
+	return eq.numPending()
 }
 
 func (eq *externalQueue[T, R]) Worker() Worker[T, R] {
@@ -82,6 +90,24 @@ func (eq *externalQueue[T, R]) GroupsJobById(id string) (EnqueuedSingleGroupJob[
 	return nil, fmt.Errorf("groups job not found for id: %s", id)
 }
 
+func (eq *externalQueue[T, R]) CancelJob(id string) error {
+	val, ok := eq.Cache.Load(id)
+
+	if !ok && !strings.HasPrefix(id, groupIdPrefixed) {
+		val, ok = eq.Cache.Load(generateGroupId(id))
+	}
+
+	if !ok {
+		return fmt.Errorf("job not found for id: %s", id)
+	}
+
+	return val.(iJob[T, R]).cancel()
+}
+
+func (eq *externalQueue[T, R]) ReplayDeadLetters(filter func(DeadLetter[T]) bool) (int, error) {
+	return eq.replayDeadLetters(filter)
+}
+
 func (eq *externalQueue[T, R]) WaitUntilFinished() {
 	// to ignore deadlock error if the queue is paused
 	if eq.IsPaused() {
@@ -99,13 +125,31 @@ func (eq *externalQueue[T, R]) WaitUntilFinished() {
 func (eq *externalQueue[T, R]) Purge() {
 	prevValues := eq.Queue.Values()
 	eq.Queue.Purge()
+	eq.pending.clear()
 
-	// close all pending channels to avoid routine leaks
+	// finish all pending jobs to avoid routine leaks of those waiting for their results.
+	// A job dequeued in the meantime is claimed by either the purge or the worker, never by both.
 	for _, val := range prevValues {
 		if j, ok := val.(iJob[T, R]); ok {
-			j.CloseResultChannel()
+			j.abort(ErrJobPurged, true)
 		}
 	}
+
+	for _, j := range eq.delayed.Purge() {
+		j.abort(ErrJobPurged, true)
+	}
+
+	for _, j := range eq.deps.Purge() {
+		j.abort(ErrJobPurged, true)
+	}
+
+	// parked jobs are already pulled from the queue, so they're counted by the wait group
+	for _, j := range eq.keys.Purge() {
+		j.abort(ErrJobPurged, true)
+		eq.wg.Done()
+	}
+
+	eq.notifySpace()
 }
 
 func (q *externalQueue[T, R]) Close() error {
@@ -0,0 +1,234 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// errNoFutures is the error of Any and Race those are given nothing to wait for
+var errNoFutures = errors.New("no job to wait for")
+
+// Future is the eventual outcome of a job, or of a combination of jobs, see All, Any and Race.
+type Future[R any] interface {
+	// Result blocks until the job completes and returns the result and any error.
+	// It doesn't consume the result, so any number of goroutines can wait for the same job.
+	Result() (R, error)
+	// ResultCtx is like Result, but it returns the context error if the context is done before the job completes.
+	ResultCtx(ctx context.Context) (R, error)
+	// Done returns a channel those is closed once the job completes, then Result returns right away.
+	Done() <-chan struct{}
+	// Then calls the given function with the result once the job completes, right away if it's already completed.
+	// The function is called by the goroutine those completes the job, so it must not block for long.
+	Then(fn func(R, error))
+	// Cancel cancels the job, Result returns ErrJobCancelled afterwards.
+	// It returns an error if the job is already finished or cancelled.
+	Cancel() error
+}
+
+// future is a Future resolved once, those outcome can be waited for by any number of goroutines
+type future[R any] struct {
+	done     chan struct{}
+	output   Result[R]
+	resolved bool
+	watchers []func(Result[R])
+	cancel   func() error
+	mx       sync.Mutex
+}
+
+func newFuture[R any](cancel func() error) *future[R] {
+	return &future[R]{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+}
+
+// resolve sets the outcome of the future and calls its watchers, only the first outcome counts
+func (f *future[R]) resolve(r Result[R]) bool {
+	f.mx.Lock()
+
+	if f.resolved {
+		f.mx.Unlock()
+		return false
+	}
+
+	f.resolved = true
+	f.output = r
+	watchers := f.watchers
+	f.watchers = nil
+	close(f.done)
+	f.mx.Unlock()
+
+	for _, fn := range watchers {
+		fn(r)
+	}
+
+	return true
+}
+
+// isResolved reports whether the outcome is set
+func (f *future[R]) isResolved() bool {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return f.resolved
+}
+
+// watch calls the given function with the outcome once it's resolved
+func (f *future[R]) watch(fn func(Result[R])) {
+	f.mx.Lock()
+
+	if !f.resolved {
+		f.watchers = append(f.watchers, fn)
+		f.mx.Unlock()
+		return
+	}
+
+	r := f.output
+	f.mx.Unlock()
+
+	fn(r)
+}
+
+func (f *future[R]) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *future[R]) Result() (R, error) {
+	<-f.done
+	return f.output.Data, f.output.Err
+}
+
+func (f *future[R]) ResultCtx(ctx context.Context) (R, error) {
+	select {
+	case <-f.done:
+		return f.output.Data, f.output.Err
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+func (f *future[R]) Then(fn func(R, error)) {
+	f.watch(func(r Result[R]) {
+		fn(r.Data, r.Err)
+	})
+}
+
+func (f *future[R]) Cancel() error {
+	if f.cancel == nil {
+		return errors.New("job can't be cancelled")
+	}
+
+	return f.cancel()
+}
+
+// cancelAll returns the function those cancels the given futures, it fails if none of them is cancelled
+func cancelAll[R any](futures []Future[R]) func() error {
+	return func() error {
+		cancelled := 0
+
+		for _, f := range futures {
+			if f.Cancel() == nil {
+				cancelled++
+			}
+		}
+
+		if cancelled == 0 {
+			return errors.New("no job left to cancel")
+		}
+
+		return nil
+	}
+}
+
+// All returns a future those resolves with the results of all the given futures in the same order once they succeed,
+// or with the first error as soon as one of them fails.
+// Cancel of the returned future cancels all the jobs those are not finished yet.
+func All[R any](futures ...Future[R]) Future[[]R] {
+	f := newFuture[[]R](cancelAll(futures))
+	results := make([]R, len(futures))
+
+	var left atomic.Int64
+	left.Store(int64(len(futures)))
+
+	if len(futures) == 0 {
+		f.resolve(Result[[]R]{Data: results})
+		return f
+	}
+
+	for i, fu := range futures {
+		fu.Then(func(data R, err error) {
+			if err != nil {
+				f.resolve(Result[[]R]{Err: err})
+				return
+			}
+
+			results[i] = data
+
+			if left.Add(-1) == 0 {
+				f.resolve(Result[[]R]{Data: results})
+			}
+		})
+	}
+
+	return f
+}
+
+// Any returns a future those resolves with the result of the first succeeded one of the given futures,
+// or with the errors of all of them joined if none succeeds.
+// Cancel of the returned future cancels all the jobs those are not finished yet, e.g. the slower ones.
+func Any[R any](futures ...Future[R]) Future[R] {
+	f := newFuture[R](cancelAll(futures))
+
+	if len(futures) == 0 {
+		f.resolve(Result[R]{Err: errNoFutures})
+		return f
+	}
+
+	var mx sync.Mutex
+	errs := make([]error, len(futures))
+	left := len(futures)
+
+	for i, fu := range futures {
+		fu.Then(func(data R, err error) {
+			if err == nil {
+				f.resolve(Result[R]{Data: data})
+				return
+			}
+
+			mx.Lock()
+			errs[i] = err
+			left--
+			failed := left == 0
+			mx.Unlock()
+
+			if failed {
+				f.resolve(Result[R]{Err: errors.Join(errs...)})
+			}
+		})
+	}
+
+	return f
+}
+
+// Race returns a future those resolves with the outcome of the first finished one of the given futures,
+// whether it's succeeded or failed.
+// Cancel of the returned future cancels all the jobs those are not finished yet, e.g. the losers of the race.
+func Race[R any](futures ...Future[R]) Future[R] {
+	f := newFuture[R](cancelAll(futures))
+
+	if len(futures) == 0 {
+		f.resolve(Result[R]{Err: errNoFutures})
+		return f
+	}
+
+	for _, fu := range futures {
+		fu.Then(func(data R, err error) {
+			f.resolve(Result[R]{Data: data, Err: err})
+		})
+	}
+
+	return f
+}
@@ -0,0 +1,207 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuture(t *testing.T) {
+	double := func(data int) (int, error) {
+		return data * 2, nil
+	}
+
+	errFailed := errors.New("failed")
+
+	t.Run("Then is called once the job completes", func(t *testing.T) {
+		w := NewWorker(double)
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		job, _ := q.Add(2)
+
+		results := make(chan int, 2)
+		job.Then(func(result int, err error) {
+			assert.NoError(t, err)
+			results <- result
+		})
+
+		assert.NoError(t, w.Resume())
+		assert.Equal(t, 4, <-results)
+
+		// the result is still there for Result and the later callbacks
+		job.Then(func(result int, err error) {
+			results <- result
+		})
+		assert.Equal(t, 4, <-results)
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+	})
+
+	t.Run("Then is called once a job without result completes", func(t *testing.T) {
+		q := NewErrWorker(func(data int) error {
+			return nil
+		}).BindQueue()
+		defer q.WaitAndClose()
+
+		job, _ := q.Add(1)
+
+		done := make(chan error, 1)
+		job.Then(func(_ any, err error) {
+			done <- err
+		})
+
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("ResultCtx returns the context error", func(t *testing.T) {
+		w := NewWorker(double)
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		job, _ := q.Add(2)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := job.ResultCtx(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("ResultCtx can be waited for by many goroutines", func(t *testing.T) {
+		w := NewWorker(double)
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		job, _ := q.Add(2)
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				result, err := job.ResultCtx(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, 4, result)
+			}()
+		}
+
+		assert.NoError(t, w.Resume())
+		wg.Wait()
+	})
+
+	t.Run("All resolves with the results in order", func(t *testing.T) {
+		futures := []*future[int]{newFuture[int](nil), newFuture[int](nil), newFuture[int](nil)}
+		all := All[int](futures[0], futures[1], futures[2])
+
+		futures[2].resolve(Result[int]{Data: 3})
+		futures[0].resolve(Result[int]{Data: 1})
+
+		_, err := all.ResultCtx(ctxDone())
+		assert.ErrorIs(t, err, context.Canceled, "all should wait for every future")
+
+		futures[1].resolve(Result[int]{Data: 2})
+
+		results, err := all.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, results)
+	})
+
+	t.Run("All fails with the first error", func(t *testing.T) {
+		futures := []*future[int]{newFuture[int](nil), newFuture[int](nil)}
+		all := All[int](futures[0], futures[1])
+
+		futures[1].resolve(Result[int]{Err: errFailed})
+
+		_, err := all.Result()
+		assert.ErrorIs(t, err, errFailed)
+	})
+
+	t.Run("All of nothing succeeds", func(t *testing.T) {
+		results, err := All[int]().Result()
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("Any resolves with the first success", func(t *testing.T) {
+		futures := []*future[int]{newFuture[int](nil), newFuture[int](nil), newFuture[int](nil)}
+		anyOf := Any[int](futures[0], futures[1], futures[2])
+
+		futures[0].resolve(Result[int]{Err: errFailed})
+		futures[2].resolve(Result[int]{Data: 3})
+		futures[1].resolve(Result[int]{Data: 2})
+
+		result, err := anyOf.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result)
+	})
+
+	t.Run("Any fails once all fail", func(t *testing.T) {
+		errOther := errors.New("other")
+		futures := []*future[int]{newFuture[int](nil), newFuture[int](nil)}
+		anyOf := Any[int](futures[0], futures[1])
+
+		futures[0].resolve(Result[int]{Err: errFailed})
+		futures[1].resolve(Result[int]{Err: errOther})
+
+		_, err := anyOf.Result()
+		assert.ErrorIs(t, err, errFailed)
+		assert.ErrorIs(t, err, errOther)
+
+		_, err = Any[int]().Result()
+		assert.Error(t, err)
+	})
+
+	t.Run("Race resolves with the first outcome", func(t *testing.T) {
+		futures := []*future[int]{newFuture[int](nil), newFuture[int](nil)}
+		race := Race[int](futures[0], futures[1])
+
+		futures[1].resolve(Result[int]{Err: errFailed})
+		futures[0].resolve(Result[int]{Data: 1})
+
+		_, err := race.Result()
+		assert.ErrorIs(t, err, errFailed)
+	})
+
+	t.Run("Cancel cancels the jobs left", func(t *testing.T) {
+		first := NewWorker(double).BindQueue()
+		defer first.WaitAndClose()
+
+		w := NewWorker(double)
+		second := w.BindQueue()
+		w.Pause()
+		defer second.Close()
+
+		fast, _ := first.Add(1)
+		slow, _ := second.Add(2)
+
+		race := Race(fast, slow)
+		result, err := race.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result)
+
+		assert.NoError(t, race.Cancel())
+		_, err = slow.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+
+		assert.Error(t, race.Cancel(), "no job should be left to cancel")
+	})
+}
+
+// ctxDone returns a context those is already done
+func ctxDone() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	return ctx
+}
@@ -1,15 +1,39 @@
 package varmq
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // groupJob represents a job that can be used in a group.
 type groupJob[T, R any] struct {
 	job[T, R]
-	done chan struct{}
-	len  *atomic.Uint32
+	done    chan struct{}
+	len     *atomic.Uint32
+	members *groupMembers[T, R]
+}
+
+// groupMembers keeps all the jobs of a group to cancel them together
+type groupMembers[T, R any] struct {
+	jobs []*groupJob[T, R]
+	mx   sync.Mutex
+}
+
+func (m *groupMembers[T, R]) add(j *groupJob[T, R]) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.jobs = append(m.jobs, j)
+}
+
+func (m *groupMembers[T, R]) values() []*groupJob[T, R] {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]*groupJob[T, R](nil), m.jobs...)
 }
 
 const groupIdPrefixed = "g:"
@@ -19,8 +43,9 @@ func newGroupJob[T, R any](bufferSize int) *groupJob[T, R] {
 		job: job[T, R]{
 			resultChannel: newResultChannel[R](bufferSize),
 		},
-		done: make(chan struct{}),
-		len:  new(atomic.Uint32),
+		done:    make(chan struct{}),
+		len:     new(atomic.Uint32),
+		members: new(groupMembers[T, R]),
 	}
 
 	gj.len.Add(uint32(bufferSize))
@@ -33,15 +58,28 @@ func generateGroupId(id string) string {
 }
 
 func (gj *groupJob[T, R]) NewJob(data T, config jobConfigs) *groupJob[T, R] {
-	return &groupJob[T, R]{
+	j := &groupJob[T, R]{
 		job: job[T, R]{
 			id:            generateGroupId(config.Id),
 			Input:         data,
 			resultChannel: gj.resultChannel,
+			deadline:      config.Deadline,
+			timeout:       config.Timeout,
+			cost:          config.Cost,
+			key:           config.Key,
+			dependency:    config.Dependency,
+			retry:         config.Retry,
+			createdAt:     time.Now(),
+			runAt:         config.RunAt,
 		},
-		done: gj.done,
-		len:  gj.len,
+		done:    gj.done,
+		len:     gj.len,
+		members: gj.members,
 	}
+
+	gj.members.add(j)
+
+	return j
 }
 
 func (gj *groupJob[T, R]) Results() (<-chan Result[R], error) {
@@ -83,6 +121,23 @@ func (gj *groupJob[T, R]) Drain() error {
 	return nil
 }
 
+// Cancel cancels all the pending and running jobs of the group.
+func (gj *groupJob[T, R]) Cancel() error {
+	cancelled := 0
+
+	for _, j := range gj.members.values() {
+		if j.cancel() == nil {
+			cancelled++
+		}
+	}
+
+	if cancelled == 0 {
+		return errors.New("group has no job to cancel")
+	}
+
+	return nil
+}
+
 func (gj *groupJob[T, R]) close() error {
 	if err := gj.isCloseable(); err != nil {
 		return err
@@ -1,5 +1,7 @@
 package varmq
 
+import "time"
+
 // IBaseQueue is the root interface of queue operations. workers queue needs to implement this interface.
 type IBaseQueue interface {
 	Len() int
@@ -30,6 +32,31 @@ type IAcknowledgeable interface {
 	DequeueWithAckId() (any, bool, string)
 }
 
+// IRemovable is the optional interface of queues those can remove a pending item, used to cancel pending jobs.
+// Items of persistent queues are jobs serialized by the codec of the worker, see WithCodec.
+type IRemovable interface {
+	// Remove removes the first item matching the given function.
+	// Returns true if an item was removed, false otherwise.
+	Remove(match func(item any) bool) bool
+}
+
+// INackable is the optional interface of acknowledgeable queues those can negatively acknowledge an item,
+// used to hand the failed jobs back to the queue instead of acknowledging them, see WithNackPolicy.
+type INackable interface {
+	// Nack negatively acknowledges the item. It's redelivered after the delay if requeue is true,
+	// otherwise it's discarded or dead lettered by the queue.
+	// Returns true if the item was successfully negatively acknowledged, false otherwise.
+	Nack(ackID string, requeue bool, delay time.Duration) bool
+}
+
+// ILeaseExtendable is the optional interface of acknowledgeable queues those redeliver the items
+// those are not acknowledged in time, e.g. once the consumer has crashed, see WithLease.
+type ILeaseExtendable interface {
+	// ExtendLease keeps the item from being redelivered for the given duration from now on.
+	// Returns false if the lease is lost, e.g. the item is already acknowledged or redelivered.
+	ExtendLease(ackID string, d time.Duration) bool
+}
+
 type IPersistentQueue interface {
 	IQueue
 	IAcknowledgeable
@@ -57,3 +84,15 @@ type IDistributedPriorityQueue interface {
 	IPersistentPriorityQueue
 	ISubscribable
 }
+
+// IResultBackend is the interface of the stores those keep the serialized results of the jobs by their ids,
+// so the results of persistent and distributed queues can be fetched by another process, see WithResultBackend.
+type IResultBackend interface {
+	// Store stores the result of the job with the given id, and keeps it for the given ttl, forever if it's zero.
+	Store(id string, result []byte, ttl time.Duration) error
+	// Fetch returns the result of the job with the given id, false if it's not stored or expired.
+	Fetch(id string) ([]byte, bool, error)
+	// Subscribe calls the given function once the result of the job with the given id is stored,
+	// right away if it's already stored. The returned function stops the subscription.
+	Subscribe(id string, fn func(result []byte)) (unsubscribe func())
+}
@@ -81,6 +81,22 @@ func (q *PriorityQueue[T]) Dequeue() (any, bool) {
 	return popped.Value, true
 }
 
+// Remove removes the first item matching the given function.
+// Time complexity: O(n)
+func (q *PriorityQueue[T]) Remove(match func(item any) bool) bool {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	for i, item := range q.internal.items {
+		if match(item.Value) {
+			heap.Remove(q.internal, i) // O(log n)
+			return true
+		}
+	}
+
+	return false
+}
+
 func (q *PriorityQueue[T]) Purge() {
 	q.mx.Lock()
 	defer q.mx.Unlock()
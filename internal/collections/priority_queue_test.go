@@ -111,4 +111,23 @@ func TestPriorityQueue(t *testing.T) {
 		pq.Enqueue(3, 1)
 		assert.Equal(1, pq.Len(), "queue should have length 1 after adding to closed queue")
 	})
+
+	t.Run("Remove Method", func(t *testing.T) {
+		assert := assert.New(t)
+		pq := NewPriorityQueue[int]()
+
+		for i := 1; i <= 5; i++ {
+			pq.Enqueue(i, i)
+		}
+
+		assert.True(pq.Remove(func(item any) bool { return item == 1 }), "matching item should be removed")
+		assert.False(pq.Remove(func(item any) bool { return item == 10 }), "missing item should not be removed")
+		assert.Equal(4, pq.Len())
+
+		for i := 2; i <= 5; i++ {
+			val, ok := pq.Dequeue()
+			assert.True(ok)
+			assert.Equal(i, val, "heap order should be kept after remove")
+		}
+	})
 }
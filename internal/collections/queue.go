@@ -90,6 +90,32 @@ func (q *Queue[T]) Dequeue() (any, bool) {
 	return item, true
 }
 
+// Remove removes the first item matching the given function while preserving the order of the others
+// Time complexity: O(n)
+func (q *Queue[T]) Remove(match func(item any) bool) bool {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	for i := range q.size {
+		if !match(q.elements[(q.front+i)%len(q.elements)]) {
+			continue
+		}
+
+		// shift the following elements to fill the gap
+		for k := i; k < q.size-1; k++ {
+			q.elements[(q.front+k)%len(q.elements)] = q.elements[(q.front+k+1)%len(q.elements)]
+		}
+
+		var zeroValue T
+		q.elements[(q.front+q.size-1)%len(q.elements)] = zeroValue
+		q.size--
+
+		return true
+	}
+
+	return false
+}
+
 // resize changes the capacity of the queue while preserving the order of elements
 // This is a helper function used internally
 func (q *Queue[T]) resize(newCapacity int) {
@@ -278,4 +278,24 @@ func TestQueue(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("Remove", func(t *testing.T) {
+		assert := assert.New(t)
+		q := NewQueue[int]()
+
+		// wrap around the ring buffer before removing
+		for i := 0; i < 98; i++ {
+			q.Enqueue(0)
+			q.Dequeue()
+		}
+
+		for i := 1; i <= 5; i++ {
+			q.Enqueue(i)
+		}
+
+		assert.True(q.Remove(func(item any) bool { return item == 3 }), "matching item should be removed")
+		assert.False(q.Remove(func(item any) bool { return item == 10 }), "missing item should not be removed")
+		assert.Equal(4, q.Len())
+		assert.Equal([]any{1, 2, 4, 5}, q.Values(), "order should be preserved after remove")
+	})
 }
@@ -1,10 +1,13 @@
 package varmq
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const (
@@ -30,14 +33,50 @@ type job[T, R any] struct {
 	resultChannel resultChannel[R]
 	queue         IBaseQueue
 	ackId         string
+	deadline      time.Time
+	timeout       time.Duration
+	cost          int
+	key           string
+	dependency    dependency
+	priority      int
+	attempts      atomic.Uint32
+	retry         retryPolicy
+	lastErr       error
+	createdAt     time.Time
+	runAt         time.Time
+	delay         time.Duration
+	replyTo       string
+	// cancelled, cancelCause, cancelRun, onCancel, ackId, queuedAt, lastErr, outcome, watchers and done are guarded by mx
+	queuedAt    time.Time
+	cancelled   bool
+	cancelCause error
+	cancelRun   context.CancelCauseFunc
+	onCancel    func()
+	outcome     *Result[R]
+	watchers    []func(Result[R])
+	done        chan struct{}
+	mx          sync.Mutex
+	// announced is set once the enqueued event is emitted, it's written under announceMx
+	announced  atomic.Bool
+	announceMx sync.Mutex
 }
 
 // jobView represents a view of a job's state for serialization.
 type jobView[T, R any] struct {
-	Id     string    `json:"id"`
-	Status string    `json:"status"`
-	Input  T         `json:"input"`
-	Output Result[R] `json:"output,omitempty"`
+	Id       string        `json:"id"`
+	Status   string        `json:"status"`
+	Input    T             `json:"input"`
+	Output   resultView[R] `json:"output,omitempty"`
+	Priority int           `json:"priority,omitempty"`
+	Attempts int           `json:"attempts"`
+	// MaxAttempts is the max attempts of the retry policy, its backoff function can't be serialized
+	MaxAttempts int           `json:"max_attempts,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	RunAt       *time.Time    `json:"run_at,omitempty"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+	Cost        int           `json:"cost,omitempty"`
+	Key         string        `json:"key,omitempty"`
+	ReplyTo     string        `json:"reply_to,omitempty"`
 }
 
 type Job interface {
@@ -56,13 +95,44 @@ type Job interface {
 type iJob[T, R any] interface {
 	Job
 	ChangeStatus(s status)
+	ChangeStatusFrom(from, to status) bool
 	SetAckId(id string)
+	AckId() string
 	SetInternalQueue(q IBaseQueue)
+	BindContext(parent context.Context) (context.Context, context.CancelFunc)
+	StartProcessing() (ok, finish bool)
+	OnCancel(fn func())
+	IsCancelled() bool
+	CancelCause() error
+	cancel() error
+	drop() bool
+	announce(fn func())
+	isAnnounced() bool
+	abort(cause error, pendingOnly bool) error
+	SetPriority(priority int)
+	Priority() int
+	NewAttempt() int
+	Attempts() int
+	RetryPolicy() retryPolicy
+	Timeout() time.Duration
+	Cost() int
+	Key() string
+	ReplyTo() string
+	Dependency() dependency
+	CreatedAt() time.Time
+	RunAt() time.Time
+	admit()
+	SetQueuedAt(t time.Time)
+	QueuedAt() time.Time
 	Data() T
 	CloseResultChannel()
 	SaveAndSendResult(result R)
 	SaveAndSendError(err error)
+	SetLastError(err error)
+	LastError() error
 	Ack() error
+	encode(codec Codec) ([]byte, error)
+	outcomeResult() (R, error)
 }
 
 // New creates a new job with the provided data.
@@ -73,6 +143,16 @@ func newJob[T, R any](data T, configs jobConfigs) *job[T, R] {
 		resultChannel: newResultChannel[R](1),
 		status:        atomic.Uint32{},
 		Output:        Result[R]{},
+		deadline:      configs.Deadline,
+		timeout:       configs.Timeout,
+		cost:          configs.Cost,
+		key:           configs.Key,
+		replyTo:       configs.ReplyTo,
+		dependency:    configs.Dependency,
+		retry:         configs.Retry,
+		createdAt:     time.Now(),
+		runAt:         configs.RunAt,
+		delay:         configs.Delay,
 	}
 }
 
@@ -80,19 +160,317 @@ func newJob[T, R any](data T, configs jobConfigs) *job[T, R] {
 // This is because distributed queue only available for void worker.
 func newVoidJob[T, R any](data T, configs jobConfigs) *job[T, R] {
 	return &job[T, R]{
-		id:    configs.Id,
-		Input: data,
+		id:        configs.Id,
+		Input:     data,
+		createdAt: time.Now(),
+		runAt:     configs.RunAt,
+		delay:     configs.Delay,
+		timeout:   configs.Timeout,
+		cost:      configs.Cost,
+		key:       configs.Key,
+		replyTo:   configs.ReplyTo,
 	}
 }
 
 func (j *job[T, R]) SetAckId(id string) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
 	j.ackId = id
 }
 
+func (j *job[T, R]) AckId() string {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	return j.ackId
+}
+
 func (j *job[T, R]) SetInternalQueue(q IBaseQueue) {
 	j.queue = q
 }
 
+type attemptCtxKey struct{}
+
+// AttemptFromContext returns the current attempt number (starting from 1) of the job
+// processed with the given context. It returns 0 if the context doesn't belong to a job.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptCtxKey{}).(int)
+	return attempt
+}
+
+// BindContext derives the execution context of the job from the given parent.
+// The context carries the current attempt and the job deadline if any,
+// and it's cancelled with ErrJobCancelled as the cause when the job is cancelled.
+func (j *job[T, R]) BindContext(parent context.Context) (context.Context, context.CancelFunc) {
+	parent = context.WithValue(parent, attemptCtxKey{}, j.Attempts())
+	ctx, cancel := context.WithCancelCause(parent)
+
+	j.mx.Lock()
+	j.cancelRun = cancel
+	if j.cancelled {
+		cancel(ErrJobCancelled)
+	}
+	j.mx.Unlock()
+
+	release := func() {
+		j.mx.Lock()
+		j.cancelRun = nil
+		j.mx.Unlock()
+
+		cancel(nil)
+	}
+
+	if j.deadline.IsZero() {
+		return ctx, release
+	}
+
+	ctx, cancelDeadline := context.WithDeadline(ctx, j.deadline)
+
+	return ctx, func() {
+		cancelDeadline()
+		release()
+	}
+}
+
+// StartProcessing marks the job as processing unless it's cancelled.
+// If the job is cancelled while nobody is finishing it, finish reports that the caller has to finish it.
+func (j *job[T, R]) StartProcessing() (ok, finish bool) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if !j.cancelled {
+		j.status.Store(processing)
+		return true, false
+	}
+
+	// a job cancelled while it's pending is finished by the canceller
+	if j.status.Load() == finished {
+		return false, false
+	}
+
+	j.status.Store(finished)
+
+	return false, true
+}
+
+// OnCancel sets the function to remove the job from the pending jobs and finish it when it's cancelled.
+func (j *job[T, R]) OnCancel(fn func()) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	j.onCancel = fn
+}
+
+// IsCancelled returns whether the job has been cancelled.
+func (j *job[T, R]) IsCancelled() bool {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	return j.cancelled
+}
+
+// CancelCause returns the error the job has been cancelled with, nil if it's not cancelled.
+func (j *job[T, R]) CancelCause() error {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	return j.cancelCause
+}
+
+// Cancel cancels the job. A pending job is removed from the queue right away,
+// while a running job gets its context cancelled. Either way Result returns ErrJobCancelled.
+func (j *job[T, R]) Cancel() error {
+	return j.cancel()
+}
+
+func (j *job[T, R]) cancel() error {
+	return j.abort(ErrJobCancelled, false)
+}
+
+// drop claims the pending job to be dropped with ErrQueueFull to make room for a new one.
+// Unlike cancel, the job is not finished here, the caller has to finish it.
+func (j *job[T, R]) drop() bool {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	switch j.status.Load() {
+	case processing, finished, closed:
+		return false
+	}
+
+	if j.cancelled {
+		return false
+	}
+
+	j.claim(ErrQueueFull)
+
+	return true
+}
+
+// claim marks the pending job as cancelled with the given cause and finished, so nobody else finishes it.
+// It must be called with the lock held.
+func (j *job[T, R]) claim(cause error) {
+	j.cancelled = true
+	j.cancelCause = cause
+	j.status.Store(finished)
+}
+
+// abort cancels the job with the given cause, a running job is aborted only if pendingOnly is false.
+func (j *job[T, R]) abort(cause error, pendingOnly bool) error {
+	j.mx.Lock()
+
+	if j.cancelled {
+		j.mx.Unlock()
+		return errors.New("job is already cancelled")
+	}
+
+	switch j.status.Load() {
+	case finished, closed:
+		j.mx.Unlock()
+		return errors.New("job is already finished")
+	case processing:
+		if pendingOnly {
+			j.mx.Unlock()
+			return errors.New("job is processing")
+		}
+
+		j.cancelled = true
+		j.cancelCause = cause
+		if j.cancelRun != nil {
+			j.cancelRun(cause)
+		}
+
+		j.mx.Unlock()
+		return nil
+	}
+
+	// claim the pending job to finish it here
+	j.claim(cause)
+	onCancel := j.onCancel
+	j.mx.Unlock()
+
+	if onCancel != nil {
+		onCancel()
+		return nil
+	}
+
+	j.SaveAndSendError(cause)
+	j.close()
+
+	return nil
+}
+
+// announce calls the given function only once, e.g. to emit the enqueued event of the job.
+// The next callers wait until it returns, so it's done before any other event of the job.
+func (j *job[T, R]) announce(fn func()) {
+	j.announceMx.Lock()
+	defer j.announceMx.Unlock()
+
+	if j.announced.Load() {
+		return
+	}
+
+	fn()
+	j.announced.Store(true)
+}
+
+// isAnnounced reports whether the announcement is done, so the callers can skip building it
+func (j *job[T, R]) isAnnounced() bool {
+	return j.announced.Load()
+}
+
+func (j *job[T, R]) SetPriority(priority int) {
+	j.priority = priority
+}
+
+// Priority returns the priority the job has been enqueued with.
+func (j *job[T, R]) Priority() int {
+	return j.priority
+}
+
+// NewAttempt increments the attempt counter of the job and returns the current attempt.
+func (j *job[T, R]) NewAttempt() int {
+	return int(j.attempts.Add(1))
+}
+
+// Attempts returns the number of times the job has been attempted.
+func (j *job[T, R]) Attempts() int {
+	return int(j.attempts.Load())
+}
+
+// CreatedAt returns the time when the job was created.
+func (j *job[T, R]) CreatedAt() time.Time {
+	return j.createdAt
+}
+
+// RunAt returns the time when the job is due to be processed, zero if it's not scheduled.
+func (j *job[T, R]) RunAt() time.Time {
+	return j.runAt
+}
+
+// admit resolves the delay of the job to its due time, it's called once the job is admitted into the queue.
+func (j *job[T, R]) admit() {
+	if j.delay > 0 {
+		j.runAt, j.delay = time.Now().Add(j.delay), 0
+	}
+}
+
+// SetQueuedAt sets the time when the job is enqueued again, e.g. to be retried.
+func (j *job[T, R]) SetQueuedAt(t time.Time) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	j.queuedAt = t
+}
+
+// QueuedAt returns the time since the job is waiting in the queue to be processed.
+func (j *job[T, R]) QueuedAt() time.Time {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if !j.queuedAt.IsZero() {
+		return j.queuedAt
+	}
+
+	// a scheduled job is not waiting before it's due
+	if j.runAt.After(j.createdAt) {
+		return j.runAt
+	}
+
+	return j.createdAt
+}
+
+// Cost returns how many tokens of the rate limit the job takes.
+func (j *job[T, R]) Cost() int {
+	return max(j.cost, 1)
+}
+
+// Key returns the key those the per key limits are applied by, empty if it has no key.
+func (j *job[T, R]) Key() string {
+	return j.key
+}
+
+// ReplyTo returns the address of the queue those the result is replied into, empty if nobody waits for a reply.
+func (j *job[T, R]) ReplyTo() string {
+	return j.replyTo
+}
+
+// Dependency returns the jobs those the job depends on, and the policy on their failure.
+func (j *job[T, R]) Dependency() dependency {
+	return j.dependency
+}
+
+// Timeout returns the execution timeout of the job, zero if it has no timeout.
+func (j *job[T, R]) Timeout() time.Duration {
+	return j.timeout
+}
+
+// RetryPolicy returns the retry policy of the job.
+func (j *job[T, R]) RetryPolicy() retryPolicy {
+	return j.retry
+}
+
 func (j *job[T, R]) ID() string {
 	return j.id
 }
@@ -103,7 +481,12 @@ func (j *job[T, R]) Data() T {
 
 // State returns the current status of the job as a string.
 func (j *job[T, R]) Status() string {
-	switch j.status.Load() {
+	return statusName(j.status.Load())
+}
+
+// statusName returns the name of the given job status.
+func statusName(s status) string {
+	switch s {
 	case created:
 		return "Created"
 	case queued:
@@ -129,11 +512,17 @@ func (j *job[T, R]) ChangeStatus(s status) {
 	j.status.Store(s)
 }
 
+// ChangeStatusFrom updates the job's status only if it's still the given one, and reports whether it's updated.
+func (j *job[T, R]) ChangeStatusFrom(from, to status) bool {
+	return j.status.CompareAndSwap(from, to)
+}
+
 // SaveAndSendResult saves the result and sends it to the job's result channel.
 func (j *job[T, R]) SaveAndSendResult(result R) {
 	r := Result[R]{JobId: j.id, Data: result}
 	j.Output = r
 	j.resultChannel.Send(r)
+	j.settle(r)
 }
 
 // SaveAndSendError sends an error to the job's result channel.
@@ -141,19 +530,116 @@ func (j *job[T, R]) SaveAndSendError(err error) {
 	r := Result[R]{JobId: j.id, Err: err}
 	j.Output = r
 	j.resultChannel.Send(r)
+	j.settle(r)
+}
+
+// watch calls the given function with the outcome of the job once it's finished, right away if it's already finished.
+// Unlike Result, it doesn't consume the result. The function is called by the goroutine those finishes the job,
+// so it must not block for long.
+func (j *job[T, R]) watch(fn func(Result[R])) {
+	j.mx.Lock()
+
+	if j.outcome == nil {
+		j.watchers = append(j.watchers, fn)
+		j.mx.Unlock()
+		return
+	}
+
+	r := *j.outcome
+	j.mx.Unlock()
+
+	fn(r)
+}
+
+// settle calls the watchers with the outcome of the job and closes its done channel, only the first outcome counts
+func (j *job[T, R]) settle(r Result[R]) {
+	j.mx.Lock()
+
+	if j.outcome != nil {
+		j.mx.Unlock()
+		return
+	}
+
+	j.outcome = &r
+	watchers := j.watchers
+	j.watchers = nil
+	close(j.doneChannel())
+	j.mx.Unlock()
+
+	for _, fn := range watchers {
+		fn(r)
+	}
+}
+
+// SetLastError keeps the error of the last failed attempt of the job.
+func (j *job[T, R]) SetLastError(err error) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	j.lastErr = err
+}
+
+// LastError returns the error of the last failed attempt of the job if any.
+func (j *job[T, R]) LastError() error {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	return j.lastErr
 }
 
 // Result blocks until the job completes and returns the result and any error.
-// If the job's result channel is closed without a value, it returns the zero value
-// and any error from the error channel.
+// It doesn't consume the result, so any number of goroutines can wait for the same job.
 func (j *job[T, R]) Result() (R, error) {
-	result, ok := <-j.resultChannel.ch
+	<-j.Done()
+	return j.outcomeResult()
+}
+
+// doneChannel returns the channel closed once the job is settled, it's created on demand since most jobs are never waited for.
+// It must be called with the lock held.
+func (j *job[T, R]) doneChannel() chan struct{} {
+	if j.done == nil {
+		j.done = make(chan struct{})
+	}
+
+	return j.done
+}
+
+// Done returns a channel those is closed once the job completes, then Result returns right away.
+func (j *job[T, R]) Done() <-chan struct{} {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	return j.doneChannel()
+}
+
+// outcomeResult returns the result and the error of the settled job, or the saved output if it's not settled
+func (j *job[T, R]) outcomeResult() (R, error) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if j.outcome == nil {
+		return j.Output.Data, j.Output.Err
+	}
 
-	if ok {
-		return result.Data, result.Err
+	return j.outcome.Data, j.outcome.Err
+}
+
+// ResultCtx is like Result, but it returns the context error if the context is done before the job completes.
+func (j *job[T, R]) ResultCtx(ctx context.Context) (R, error) {
+	select {
+	case <-j.Done():
+		return j.outcomeResult()
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
 	}
+}
 
-	return j.Output.Data, j.Output.Err
+// Then calls the given function with the result once the job completes, right away if it's already completed.
+func (j *job[T, R]) Then(fn func(R, error)) {
+	j.watch(func(r Result[R]) {
+		fn(r.Data, r.Err)
+	})
 }
 
 // Drain discards the job's result and error values asynchronously.
@@ -191,29 +677,80 @@ func (j *job[T, R]) isCloseable() error {
 }
 
 func (j *job[T, R]) Json() ([]byte, error) {
+	return json.Marshal(j.view())
+}
+
+// encode serializes the job with the given codec into a payload of persistent and distributed queues
+func (j *job[T, R]) encode(codec Codec) ([]byte, error) {
+	return encodePayload(codec, j.view())
+}
+
+func (j *job[T, R]) view() jobView[T, R] {
 	view := jobView[T, R]{
-		Id:     j.ID(),
-		Status: j.Status(),
-		Input:  j.Input,
-		Output: j.Output,
+		Id:          j.ID(),
+		Status:      j.Status(),
+		Input:       j.Input,
+		Output:      newResultView(j.Output),
+		Priority:    j.priority,
+		Attempts:    j.Attempts(),
+		MaxAttempts: j.retry.MaxAttempts,
+		CreatedAt:   j.createdAt,
+		Timeout:     j.timeout,
+		Cost:        j.cost,
+		Key:         j.key,
+		ReplyTo:     j.replyTo,
+	}
+
+	if !j.runAt.IsZero() {
+		view.RunAt = &j.runAt
 	}
 
-	return json.Marshal(view)
+	return view
 }
 
-func parseToJob[T, R any](data []byte) (iJob[T, R], error) {
+// parseJobId returns the id of the serialized job without parsing the whole job, empty if it's unparsable.
+func parseJobId(data []byte, codec Codec) string {
+	var view struct {
+		Id string `json:"id"`
+	}
+
+	if err := decodePayload(codec, data, &view); err != nil {
+		return ""
+	}
+
+	return view.Id
+}
+
+// parseToJob parses the payload encoded by the given codec or a built-in one, see decodePayload.
+func parseToJob[T, R any](data []byte, codec Codec) (iJob[T, R], error) {
 	var view jobView[T, R]
-	if err := json.Unmarshal(data, &view); err != nil {
+	if err := decodePayload(codec, data, &view); err != nil {
 		return nil, fmt.Errorf("failed to parse job: %w", err)
 	}
 
 	j := &job[T, R]{
 		id:            view.Id,
 		Input:         view.Input,
-		Output:        view.Output,
+		Output:        view.Output.result(),
 		resultChannel: newResultChannel[R](1),
+		priority:      view.Priority,
+		createdAt:     view.CreatedAt,
+		timeout:       view.Timeout,
+		cost:          view.Cost,
+		key:           view.Key,
+		replyTo:       view.ReplyTo,
+		retry:         retryPolicy{MaxAttempts: view.MaxAttempts, restored: view.MaxAttempts > 0},
+	}
+
+	// the parsed job is enqueued by its producer already
+	j.announced.Store(true)
+
+	if view.RunAt != nil {
+		j.runAt = *view.RunAt
 	}
 
+	j.attempts.Store(uint32(max(view.Attempts, 0)))
+
 	// Set the status
 	switch view.Status {
 	case "Created":
@@ -243,11 +780,16 @@ func (j *job[T, R]) close() error {
 	j.resultChannel.Close()
 	j.Ack()
 	j.status.Store(closed)
+
+	// a job those function returns no result is settled once it's closed
+	j.settle(Result[R]{JobId: j.id, Data: j.Output.Data, Err: j.Output.Err})
 	return nil
 }
 
 func (j *job[T, R]) Ack() error {
-	if j.ackId == "" || j.IsClosed() {
+	ackId := j.AckId()
+
+	if ackId == "" || j.IsClosed() {
 		return errors.New("job is not acknowledgeable")
 	}
 
@@ -255,8 +797,8 @@ func (j *job[T, R]) Ack() error {
 		return errors.New("job is not acknowledgeable")
 	}
 
-	if ok := j.queue.(IAcknowledgeable).Acknowledge(j.ackId); !ok {
-		return fmt.Errorf("queue failed to acknowledge job %s (ackId=%s)", j.id, j.ackId)
+	if ok := j.queue.(IAcknowledgeable).Acknowledge(ackId); !ok {
+		return fmt.Errorf("queue failed to acknowledge job %s (ackId=%s)", j.id, ackId)
 	}
 
 	return nil
@@ -1,15 +1,26 @@
 package varmq
 
+import "time"
+
 type JobConfigFunc func(*jobConfigs)
 
 type jobConfigs struct {
-	Id string
+	Id         string
+	Deadline   time.Time
+	Timeout    time.Duration
+	Cost       int
+	Key        string
+	Dependency dependency
+	Retry      retryPolicy
+	RunAt      time.Time
+	// Delay is resolved to RunAt once the job is admitted, see WithDelay
+	Delay   time.Duration
+	ReplyTo string
 }
 
 func loadJobConfigs(qConfig configs, config ...JobConfigFunc) jobConfigs {
-	c := jobConfigs{
-		Id: qConfig.JobIdGenerator(),
-	}
+	c := qConfig.JobConfigs
+	c.Id = qConfig.JobIdGenerator()
 
 	for _, config := range config {
 		config(&c)
@@ -18,6 +29,14 @@ func loadJobConfigs(qConfig configs, config ...JobConfigFunc) jobConfigs {
 	return c
 }
 
+// loadItemJobConfigs loads the job configs of an item added by AddAll, the job id is taken from the item
+func loadItemJobConfigs(qConfig configs, id string, config ...JobConfigFunc) jobConfigs {
+	c := loadJobConfigs(qConfig, config...)
+	WithJobId(id)(&c)
+
+	return c
+}
+
 func WithJobId(id string) JobConfigFunc {
 	return func(c *jobConfigs) {
 		if id == "" {
@@ -27,6 +46,70 @@ func WithJobId(id string) JobConfigFunc {
 	}
 }
 
+// WithJobDeadline sets the deadline of the context passed to context-aware worker functions.
+// The deadline has no effect on worker functions those don't accept a context.
+func WithJobDeadline(t time.Time) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.Deadline = t
+	}
+}
+
+// WithJobTimeout sets the execution timeout of the job, it can be used as a worker config as well to apply it to all jobs.
+// When the job exceeds the timeout, it fails with ErrJobTimeout and its pool slot is freed right away.
+// Context-aware worker functions get their context cancelled, others keep running in the background until they return.
+func WithJobTimeout(d time.Duration) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.Timeout = max(d, 0)
+	}
+}
+
+// WithJobCost sets how many tokens of the rate limit the job takes, default is 1, see WithRateLimit.
+// It can be used as a worker config as well to apply it to all jobs.
+func WithJobCost(cost int) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.Cost = max(cost, 1)
+	}
+}
+
+// WithJobKey sets the key of the job, e.g. a tenant id, those the per key limits are applied by,
+// see WithMaxConcurrencyPerKey and WithRateLimitPerKey.
+func WithJobKey(key string) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.Key = key
+	}
+}
+
+// WithDelay delays the job, it won't be processed before the given duration is passed since it's admitted into the queue,
+// e.g. a job waiting for room in a bounded queue is delayed from the time it's added.
+func WithDelay(d time.Duration) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.Delay, c.RunAt = max(d, 0), time.Time{}
+	}
+}
+
+// WithRunAt schedules the job, it won't be processed before the given time.
+func WithRunAt(t time.Time) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.RunAt, c.Delay = t, 0
+	}
+}
+
+// withReplyTo sets the address of the queue those the result of the job is replied into, see AddAndWait
+func withReplyTo(address string) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.ReplyTo = address
+	}
+}
+
+// requireJobIdGenerator returns an error if the worker has no job id generator set by WithJobIdGenerator
+func (c configs) requireJobIdGenerator() error {
+	if !c.HasJobIdGenerator {
+		return errNoJobIdGenerator
+	}
+
+	return nil
+}
+
 func withRequiredJobId(c jobConfigs) jobConfigs {
 	if c.Id == "" {
 		panic("job id is required for persistent queue")
@@ -2,6 +2,7 @@ package varmq
 
 import (
 	"errors"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -127,4 +128,58 @@ func TestJob(t *testing.T) {
 		assert.NotNil(err, "closing an already closed job should fail")
 		assert.Contains(err.Error(), "already closed", "error message should indicate job is already closed")
 	})
+
+	t.Run("result can be read by many waiters", func(t *testing.T) {
+		j := newJob[string, int]("test data", jobConfigs{Id: "job-waiters"})
+		assert := assert.New(t)
+
+		select {
+		case <-j.Done():
+			assert.Fail("done should not be closed before the job completes")
+		default:
+		}
+
+		var wg sync.WaitGroup
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				result, err := j.Result()
+				assert.NoError(err)
+				assert.Equal(42, result)
+			}()
+		}
+
+		j.SaveAndSendResult(42)
+		j.close()
+		wg.Wait()
+
+		<-j.Done()
+		result, err := j.Result()
+		assert.NoError(err)
+		assert.Equal(42, result, "result should be repeatable")
+	})
+
+	t.Run("job found by id shares the result", func(t *testing.T) {
+		w := NewWorker(func(data int) (int, error) {
+			return data * 2, nil
+		}, WithCache(new(sync.Map)))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		job, _ := q.Add(2, WithJobId("shared"))
+		found, err := q.JobById("shared")
+		assert.NoError(t, err)
+
+		assert.NoError(t, w.Resume())
+		<-found.Done()
+
+		for _, j := range []EnqueuedJob[int]{job, found, job} {
+			result, err := j.Result()
+			assert.NoError(t, err)
+			assert.Equal(t, 4, result)
+		}
+	})
 }
@@ -0,0 +1,450 @@
+package varmq
+
+import (
+	"sync"
+	"time"
+)
+
+// keyLookahead is the max number of the jobs those are held aside while their keys are saturated,
+// to dispatch the jobs of the other keys behind them. The queue is not pulled further once it's reached.
+const keyLookahead = 1000
+
+// minKeySweep is the number of the key states those triggers the first sweep of the idle ones, see keyLimits.sweep
+const minKeySweep = 1024
+
+// WithMaxConcurrencyPerKey limits the number of the jobs with the same key processed at once, see WithJobKey.
+// The jobs of a saturated key stay pending while the jobs of the other keys are dispatched,
+// so a single key flooding the queue can't take every worker of the pool. Jobs without a key are not limited.
+func WithMaxConcurrencyPerKey(n int) ConfigFunc {
+	return func(c *configs) {
+		c.MaxConcurrencyPerKey = max(n, 0)
+	}
+}
+
+// WithSerialKeys runs the jobs with the same key strictly one at a time and in the order they're dequeued,
+// while the jobs of different keys still run in parallel, e.g. the updates of an account.
+// A failed job holds its key while it's retried, so the next jobs of the key never overtake it.
+func WithSerialKeys() ConfigFunc {
+	return func(c *configs) {
+		c.SerialKeys = true
+	}
+}
+
+// WithRateLimitPerKey limits the jobs with the same key to be dispatched n times per interval, with a burst of n jobs.
+// Like WithMaxConcurrencyPerKey, the jobs of a limited key don't hold back the jobs of the other keys.
+func WithRateLimitPerKey(n int, per time.Duration) ConfigFunc {
+	return WithRateLimiterPerKey(func() RateLimiter {
+		return NewTokenBucket(n, per, n)
+	})
+}
+
+// WithRateLimiterPerKey limits the jobs with the same key by a limiter created by the given function for each key.
+// The limiter of an unused key is dropped once it's fully refilled, so the keys don't pile up.
+// A custom limiter is kept as long as the worker lives though, to not to reset its limit.
+func WithRateLimiterPerKey(newLimiter func() RateLimiter) ConfigFunc {
+	return func(c *configs) {
+		c.KeyRateLimiter = newLimiter
+	}
+}
+
+// keyState is the usage of a key
+type keyState[T, R any] struct {
+	running int
+	parked  int
+	limiter RateLimiter
+	// owner is the job those holds the key while it's retried, only used for the serial keys
+	owner iJob[T, R]
+	// ownerQueued is true while the owner is in the queue to be dequeued again
+	ownerQueued bool
+}
+
+// parkedJob is a dequeued job waiting for its key to be available
+type parkedJob[T, R any] struct {
+	job   iJob[T, R]
+	ackId string
+}
+
+// idleable is implemented by the built-in rate limiters, to tell they're back to their initial state
+type idleable interface {
+	idle() bool
+}
+
+// idle reports whether the key has no running, parked or retried jobs, and its limiter would be recreated the same
+func (s *keyState[T, R]) idle() bool {
+	if s.running != 0 || s.parked != 0 || s.owner != nil {
+		return false
+	}
+
+	if s.limiter == nil {
+		return true
+	}
+
+	l, ok := s.limiter.(idleable)
+	return ok && l.idle()
+}
+
+// keyLimits applies the per key limits, and holds the jobs of the saturated keys in the order they're dequeued.
+// Keys are forgotten once they're idle, the ones those are still rate limited are swept later as the keys grow.
+type keyLimits[T, R any] struct {
+	maxConcurrency int
+	newLimiter     func() RateLimiter
+	serial         bool
+	states         map[string]*keyState[T, R]
+	parked         []parkedJob[T, R]
+	queuedOwners   int
+	waker          rateWaker
+	wake           func()
+	mx             sync.Mutex
+	// sweepAt is the number of the states those triggers the next sweep
+	sweepAt int
+}
+
+func newKeyLimits[T, R any](c configs, wake func()) *keyLimits[T, R] {
+	k := &keyLimits[T, R]{
+		maxConcurrency: c.MaxConcurrencyPerKey,
+		newLimiter:     c.KeyRateLimiter,
+		serial:         c.SerialKeys,
+		states:         make(map[string]*keyState[T, R]),
+		sweepAt:        minKeySweep,
+		wake:           wake,
+	}
+
+	if k.serial {
+		k.maxConcurrency = 1
+	}
+
+	return k
+}
+
+// enabled reports whether any per key limit is set
+func (k *keyLimits[T, R]) enabled() bool {
+	return k.maxConcurrency > 0 || k.newLimiter != nil
+}
+
+// state returns the state of the key, creating it if it doesn't exist, it must be called with the lock held
+func (k *keyLimits[T, R]) state(key string) *keyState[T, R] {
+	s, ok := k.states[key]
+
+	if !ok {
+		if len(k.states) >= k.sweepAt {
+			k.sweep()
+		}
+
+		s = &keyState[T, R]{}
+
+		if k.newLimiter != nil {
+			s.limiter = k.newLimiter()
+		}
+
+		k.states[key] = s
+	}
+
+	return s
+}
+
+// forget removes the state of the key once it's idle, it must be called with the lock held
+func (k *keyLimits[T, R]) forget(key string, s *keyState[T, R]) {
+	if s.idle() {
+		delete(k.states, key)
+	}
+}
+
+// sweep removes the states of the idle keys, those limiters have been refilled since they're last used.
+// The next sweep is once the states are doubled, so it costs constant time per key on average.
+// It must be called with the lock held.
+func (k *keyLimits[T, R]) sweep() {
+	for key, s := range k.states {
+		k.forget(key, s)
+	}
+
+	k.sweepAt = max(2*len(k.states), minKeySweep)
+}
+
+// delay returns how long the key must wait to dispatch a job, 0 if it's available now,
+// and -1 if it's saturated until one of its jobs is finished. It must be called with the lock held.
+func (k *keyLimits[T, R]) delay(key string) time.Duration {
+	s, ok := k.states[key]
+
+	if !ok {
+		return 0
+	}
+
+	if s.owner != nil || (k.maxConcurrency > 0 && s.running >= k.maxConcurrency) {
+		return -1
+	}
+
+	if s.limiter != nil {
+		return s.limiter.Delay()
+	}
+
+	return 0
+}
+
+// allowed reports whether the job can be dispatched now, jobs without a key are always allowed.
+// The jobs behind the parked ones of the same key are not allowed, to keep their order.
+func (k *keyLimits[T, R]) allowed(j iJob[T, R]) bool {
+	key := j.Key()
+
+	if !k.enabled() || key == "" {
+		return true
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	s, ok := k.states[key]
+	if !ok {
+		return true
+	}
+
+	// the retried owner goes before the parked jobs of its key, even if the key is rate limited,
+	// otherwise it would be parked behind the key those it holds
+	if s.owner == j {
+		return true
+	}
+
+	return s.parked == 0 && k.delay(key) == 0
+}
+
+// park holds the dequeued job aside until its key is available
+func (k *keyLimits[T, R]) park(j iJob[T, R], ackId string) {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	k.state(j.Key()).parked++
+	k.parked = append(k.parked, parkedJob[T, R]{job: j, ackId: ackId})
+}
+
+// ready returns the index of the oldest parked job those key is available, -1 if there is none.
+// If the keys are rate limited, the event loop is notified once the earliest one is available.
+// It must be called with the lock held.
+func (k *keyLimits[T, R]) ready() int {
+	var wait time.Duration
+	checked := make(map[string]bool)
+
+	for i, p := range k.parked {
+		key := p.job.Key()
+
+		if checked[key] {
+			continue
+		}
+
+		checked[key] = true
+		d := k.delay(key)
+
+		if d == 0 {
+			return i
+		}
+
+		if d > 0 && (wait == 0 || d < wait) {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		k.waker.wakeAfter(wait, k.wake)
+	}
+
+	return -1
+}
+
+// hasReady reports whether a parked job can be dispatched now
+func (k *keyLimits[T, R]) hasReady() bool {
+	if !k.enabled() {
+		return false
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	return k.ready() >= 0
+}
+
+// popReady removes and returns the oldest parked job those key is available
+func (k *keyLimits[T, R]) popReady() (iJob[T, R], string, bool) {
+	if !k.enabled() {
+		return nil, "", false
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	i := k.ready()
+	if i < 0 {
+		return nil, "", false
+	}
+
+	p := k.parked[i]
+	k.removeAt(i)
+
+	return p.job, p.ackId, true
+}
+
+// removeAt removes the parked job at the given index, it must be called with the lock held
+func (k *keyLimits[T, R]) removeAt(i int) {
+	key := k.parked[i].job.Key()
+	s := k.states[key]
+
+	s.parked--
+	k.parked = append(k.parked[:i], k.parked[i+1:]...)
+	k.forget(key, s)
+}
+
+// remove removes the given job if it's parked
+func (k *keyLimits[T, R]) remove(j iJob[T, R]) bool {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	for i, p := range k.parked {
+		if p.job == j {
+			k.removeAt(i)
+			return true
+		}
+	}
+
+	return false
+}
+
+// full reports whether no more job can be parked, so the queue must not be pulled further.
+// The queue is pulled regardless while a retried owner is in it, otherwise its key would never be available.
+func (k *keyLimits[T, R]) full() bool {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	return len(k.parked) >= keyLookahead && k.queuedOwners == 0
+}
+
+// Len returns the number of the parked jobs
+func (k *keyLimits[T, R]) Len() int {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	return len(k.parked)
+}
+
+// Values returns the parked jobs
+func (k *keyLimits[T, R]) Values() []iJob[T, R] {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	values := make([]iJob[T, R], 0, len(k.parked))
+	for _, p := range k.parked {
+		values = append(values, p.job)
+	}
+
+	return values
+}
+
+// Purge removes all the parked jobs and returns them
+func (k *keyLimits[T, R]) Purge() []iJob[T, R] {
+	values := k.Values()
+
+	for _, j := range values {
+		k.remove(j)
+	}
+
+	return values
+}
+
+// acquire counts the dispatched job against its key
+func (k *keyLimits[T, R]) acquire(j iJob[T, R]) {
+	key := j.Key()
+
+	if !k.enabled() || key == "" {
+		return
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	s := k.state(key)
+	s.running++
+
+	if s.owner == j {
+		k.disown(s)
+	}
+
+	if s.limiter != nil {
+		s.limiter.Take(j.Cost())
+	}
+}
+
+// hold keeps the key of the failed job held while it's retried, if the keys are serial
+func (k *keyLimits[T, R]) hold(j iJob[T, R]) {
+	key := j.Key()
+
+	if !k.serial || key == "" {
+		return
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	k.state(key).owner = j
+}
+
+// requeued marks the retried owner as it's in the queue again
+func (k *keyLimits[T, R]) requeued(j iJob[T, R]) {
+	key := j.Key()
+
+	if !k.serial || key == "" {
+		return
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	if s, ok := k.states[key]; ok && s.owner == j && !s.ownerQueued {
+		s.ownerQueued = true
+		k.queuedOwners++
+	}
+}
+
+// disown clears the owner of the key, it must be called with the lock held
+func (k *keyLimits[T, R]) disown(s *keyState[T, R]) {
+	if s.ownerQueued {
+		k.queuedOwners--
+	}
+
+	s.owner, s.ownerQueued = nil, false
+}
+
+// close frees the key held by the closed job, e.g. it's cancelled while waiting to be retried,
+// and returns true if it's freed
+func (k *keyLimits[T, R]) close(j iJob[T, R]) bool {
+	key := j.Key()
+
+	if !k.serial || key == "" {
+		return false
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	s, ok := k.states[key]
+	if !ok || s.owner != j {
+		return false
+	}
+
+	k.disown(s)
+	k.forget(key, s)
+
+	return true
+}
+
+// release frees the slot of the finished job of its key
+func (k *keyLimits[T, R]) release(j iJob[T, R]) {
+	key := j.Key()
+
+	if !k.enabled() || key == "" {
+		return
+	}
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	if s, ok := k.states[key]; ok {
+		s.running--
+		k.forget(key, s)
+	}
+}
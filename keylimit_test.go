@@ -0,0 +1,307 @@
+package varmq
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyLimits(t *testing.T) {
+	t.Run("a flooding key doesn't take every worker", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan string, 20)
+
+		w := NewVoidWorker(func(key string) {
+			started <- key
+			if key == "a" {
+				<-release
+			}
+		}, WithConcurrency(4), WithMaxConcurrencyPerKey(2))
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		for range 10 {
+			q.Add("a", WithJobKey("a"))
+		}
+
+		assert.Equal(t, "a", <-started)
+		assert.Equal(t, "a", <-started)
+
+		b, _ := q.Add("b", WithJobKey("b"))
+		_, err := b.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, "b", <-started, "the job of the other key should pass the saturated key")
+
+		assert.Equal(t, 2, w.NumProcessing(), "the saturated key should not take the free workers")
+		assert.Equal(t, 8, q.NumPending(), "the parked jobs should be counted as pending")
+
+		close(release)
+		q.WaitUntilFinished()
+		assert.Len(t, started, 8)
+	})
+
+	t.Run("keeps the order of the jobs within a key", func(t *testing.T) {
+		var mx sync.Mutex
+		order := make([]int, 0, 5)
+
+		w := NewVoidWorker(func(i int) {
+			mx.Lock()
+			order = append(order, i)
+			mx.Unlock()
+			time.Sleep(time.Millisecond)
+		}, WithConcurrency(4), WithMaxConcurrencyPerKey(1))
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		for i := range 5 {
+			q.Add(i, WithJobKey("a"))
+		}
+
+		q.WaitUntilFinished()
+
+		mx.Lock()
+		defer mx.Unlock()
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, order)
+	})
+
+	t.Run("limits the rate of each key", func(t *testing.T) {
+		w := NewWorker(func(i int) (int, error) {
+			return i, nil
+		}, WithConcurrency(2), WithRateLimitPerKey(1, time.Hour))
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		first, _ := q.Add(1, WithJobKey("a"))
+		limited, _ := q.Add(2, WithJobKey("a"))
+		other, _ := q.Add(3, WithJobKey("b"))
+		unkeyed, _ := q.Add(4)
+
+		for _, job := range []EnqueuedJob[int]{first, other, unkeyed} {
+			_, err := job.Result()
+			assert.NoError(t, err)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, "Queued", limited.Status())
+		assert.Equal(t, 1, q.NumPending())
+	})
+
+	t.Run("cancels and purges the parked jobs", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan struct{}, 1)
+
+		w := newWorker[int, any](VoidWorkerFunc[int](func(_ int) {
+			started <- struct{}{}
+			<-release
+		}), WithConcurrency(2), WithMaxConcurrencyPerKey(1))
+
+		q := newVoidQueues(w).BindQueue()
+		defer q.Close()
+
+		q.Add(1, WithJobKey("a"))
+		<-started
+
+		parked, _ := q.Add(2, WithJobKey("a"))
+		purged, _ := q.Add(3, WithJobKey("a"))
+
+		assert.Eventually(t, func() bool {
+			return w.keys.Len() == 2
+		}, time.Second, time.Millisecond, "the jobs should be parked")
+
+		assert.NoError(t, parked.Cancel())
+		_, err := parked.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+		assert.Equal(t, 1, q.NumPending())
+
+		q.Purge()
+		assert.Zero(t, q.NumPending())
+		assert.Equal(t, "Closed", purged.Status())
+
+		close(release)
+		q.WaitUntilFinished()
+	})
+}
+
+func TestSerialKeys(t *testing.T) {
+	t.Run("runs the jobs of a key one at a time in order", func(t *testing.T) {
+		var mx sync.Mutex
+		running := make(map[string]int)
+		order := make(map[string][]int)
+		overlapped := false
+
+		w := NewVoidWorker(func(i int) {
+			key := []string{"a", "b", "c"}[i%3]
+
+			mx.Lock()
+			running[key]++
+			overlapped = overlapped || running[key] > 1
+			order[key] = append(order[key], i)
+			mx.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mx.Lock()
+			running[key]--
+			mx.Unlock()
+		}, WithConcurrency(3), WithSerialKeys())
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		for i := range 30 {
+			q.Add(i, WithJobKey([]string{"a", "b", "c"}[i%3]))
+		}
+
+		q.WaitUntilFinished()
+
+		mx.Lock()
+		defer mx.Unlock()
+
+		assert.False(t, overlapped, "the jobs of a key should never run at once")
+		for k, key := range []string{"a", "b", "c"} {
+			expected := make([]int, 0, 10)
+			for i := k; i < 30; i += 3 {
+				expected = append(expected, i)
+			}
+
+			assert.Equal(t, expected, order[key])
+		}
+	})
+
+	t.Run("a retried job is not overtaken by the next jobs of its key", func(t *testing.T) {
+		var mx sync.Mutex
+		order := make([]int, 0, 4)
+		failed := false
+
+		w := NewErrWorker(func(i int) error {
+			mx.Lock()
+			defer mx.Unlock()
+
+			if i == 0 && !failed {
+				failed = true
+				return errors.New("failed")
+			}
+
+			order = append(order, i)
+			return nil
+		}, WithConcurrency(4), WithSerialKeys(), WithRetry(2, ConstantBackoff(10*time.Millisecond)))
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		for i := range 3 {
+			q.Add(i, WithJobKey("a"))
+		}
+		other, _ := q.Add(3, WithJobKey("b"))
+
+		_, err := other.Result()
+		assert.NoError(t, err)
+		q.WaitUntilFinished()
+
+		mx.Lock()
+		defer mx.Unlock()
+		assert.Equal(t, 3, order[0], "the other key should not wait for the retry")
+		assert.Equal(t, []int{0, 1, 2}, order[1:])
+	})
+
+	t.Run("frees the key once the retried job is cancelled", func(t *testing.T) {
+		w := NewErrWorker(func(i int) error {
+			if i == 0 {
+				return errors.New("failed")
+			}
+
+			return nil
+		}, WithSerialKeys(), WithRetry(2, ConstantBackoff(time.Hour)))
+
+		retrying := make(chan struct{}, 1)
+		w.OnRetry(func(Event) { retrying <- struct{}{} })
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		retried, _ := q.Add(0, WithJobKey("a"))
+		next, _ := q.Add(1, WithJobKey("a"))
+		<-retrying
+
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, "Queued", next.Status(), "the key should be held by the retried job")
+
+		assert.NoError(t, retried.Cancel())
+		_, err := next.Result()
+		assert.NoError(t, err)
+	})
+}
+
+func TestKeyLimitsForget(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	newLimiter := func() RateLimiter {
+		b := NewTokenBucket(1, time.Hour, 1).(*tokenBucket)
+		b.now = clock.now
+
+		return b
+	}
+
+	c := loadConfigs(WithRateLimiterPerKey(newLimiter))
+	k := newKeyLimits[int, int](c, func() {})
+
+	run := func(key string) {
+		j := newJob[int, int](1, loadJobConfigs(c, WithJobKey(key)))
+		k.acquire(j)
+		k.release(j)
+	}
+
+	t.Run("rate limited key is kept until its limiter is refilled", func(t *testing.T) {
+		run("a")
+		assert.Contains(t, k.states, "a", "forgetting the key would reset its limit")
+
+		clock.advance(time.Hour)
+		k.sweep()
+		assert.NotContains(t, k.states, "a")
+	})
+
+	t.Run("idle keys are swept as the keys grow", func(t *testing.T) {
+		for i := range minKeySweep {
+			run(strconv.Itoa(i))
+		}
+
+		assert.Len(t, k.states, minKeySweep)
+		clock.advance(time.Hour)
+
+		run("last")
+		assert.Len(t, k.states, 1, "the refilled keys should be swept")
+	})
+
+	t.Run("custom limiter is kept", func(t *testing.T) {
+		c := loadConfigs(WithRateLimiterPerKey(func() RateLimiter {
+			return &countingLimiter{}
+		}))
+		k := newKeyLimits[int, int](c, func() {})
+
+		j := newJob[int, int](1, loadJobConfigs(c, WithJobKey("a")))
+		k.acquire(j)
+		k.release(j)
+		k.sweep()
+
+		assert.Contains(t, k.states, "a")
+	})
+}
+
+// countingLimiter is a custom rate limiter those never delays
+type countingLimiter struct {
+	taken int
+}
+
+func (l *countingLimiter) Delay() time.Duration {
+	return 0
+}
+
+func (l *countingLimiter) Take(cost int) {
+	l.taken += cost
+}
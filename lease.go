@@ -0,0 +1,109 @@
+package varmq
+
+import (
+	"sync"
+	"time"
+)
+
+// minLease is the shortest lease those can be renewed, so the renewals don't flood the queue
+const minLease = 10 * time.Millisecond
+
+// WithLease renews the lease of the jobs of ILeaseExtendable queues from their delivery until they're settled,
+// e.g. while they're delayed, waiting for their key or processing, extending it by the given duration every half of it,
+// so they're redelivered only if the worker stops renewing, e.g. once it has crashed.
+// The lease should be shorter than the visibility timeout of the queue, and it's 10ms at least.
+func WithLease(lease time.Duration) ConfigFunc {
+	return func(c *configs) {
+		if lease <= 0 {
+			c.Lease = 0
+			return
+		}
+
+		c.Lease = max(lease, minLease)
+	}
+}
+
+// leases are the renewals of the deliveries those are held by the worker, by their ack ids
+type leases struct {
+	stops map[string]func()
+	mx    sync.Mutex
+}
+
+// keep stores the function those stops the renewal of the delivery
+func (l *leases) keep(ackId string, stop func()) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.stops == nil {
+		l.stops = make(map[string]func())
+	}
+
+	l.stops[ackId] = stop
+}
+
+// release stops renewing the lease of the delivery, once it's settled
+func (l *leases) release(ackId string) {
+	if ackId == "" {
+		return
+	}
+
+	l.mx.Lock()
+	stop, ok := l.stops[ackId]
+	delete(l.stops, ackId)
+	l.mx.Unlock()
+
+	if ok {
+		stop()
+	}
+}
+
+// releaseAll stops renewing all the leases, so the held deliveries are redelivered by the queue
+func (l *leases) releaseAll() {
+	l.mx.Lock()
+	stops := l.stops
+	l.stops = nil
+	l.mx.Unlock()
+
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+// keepLease extends the lease of the job's delivery until it's released, see leases.release
+func (w *worker[T, R]) keepLease(ackId string) {
+	lease := w.configs.Lease
+	q, ok := w.Queue.(ILeaseExtendable)
+
+	if lease <= 0 || !ok || ackId == "" {
+		return
+	}
+
+	// the delivery might have waited in the queue, so the lease is extended right away
+	if !q.ExtendLease(ackId, lease) {
+		return
+	}
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(lease / 2)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			// the lease is lost, so renewing it is pointless
+			if !q.ExtendLease(ackId, lease) {
+				return
+			}
+		}
+	}()
+
+	w.leases.keep(ackId, func() {
+		close(done)
+	})
+}
@@ -0,0 +1,114 @@
+package varmq
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLease(t *testing.T) {
+	t.Run("lease is renewed while the job is processing", func(t *testing.T) {
+		mq := NewMemoryQueue(30 * time.Millisecond)
+		var calls atomic.Int32
+		var held atomic.Bool
+
+		q := NewWorker(func(data int) (int, error) {
+			calls.Add(1)
+			time.Sleep(150 * time.Millisecond)
+			held.Store(mq.Len() == 0 && mq.Inflight() == 1)
+
+			return data * 2, nil
+		}, WithLease(20*time.Millisecond)).WithPersistentQueue(mq)
+		defer q.Close()
+
+		job, ok := q.Add(2, WithJobId("job"))
+		assert.True(t, ok)
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+
+		assert.True(t, held.Load(), "the lease should be held until the job is finished")
+		assert.Equal(t, int32(1), calls.Load(), "the job should not be redelivered")
+		assert.Equal(t, 0, mq.Len())
+		assert.Equal(t, 0, mq.Inflight())
+	})
+
+	t.Run("job of a crashed consumer is redelivered", func(t *testing.T) {
+		mq := NewMemoryQueue(20 * time.Millisecond)
+
+		producer := NewDistributedQueue[int, int](mq)
+		assert.True(t, producer.Add(1, WithJobId("job")))
+
+		// the crashed consumer never acknowledges the job
+		_, ok, _ := mq.DequeueWithAckId()
+		assert.True(t, ok)
+
+		var calls atomic.Int32
+		q := NewWorker(func(data int) (int, error) {
+			calls.Add(1)
+			return data, nil
+		}, WithLease(10*time.Millisecond)).WithDistributedQueue(mq)
+		defer q.Close()
+
+		assert.Eventually(t, func() bool {
+			return calls.Load() == 1 && mq.Inflight() == 0 && mq.Len() == 0
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("lease is renewed while the job is parked", func(t *testing.T) {
+		mq := &countingDeliveryQueue{MemoryQueue: NewMemoryQueue(30 * time.Millisecond)}
+		release := make(chan struct{})
+
+		q := NewWorker(func(data int) (int, error) {
+			if data == 1 {
+				<-release
+			}
+
+			return data, nil
+		}, 2, WithLease(20*time.Millisecond), WithMaxConcurrencyPerKey(1)).WithPersistentQueue(mq)
+		defer q.Close()
+
+		first, _ := q.Add(1, WithJobId("first"), WithJobKey("key"))
+		second, _ := q.Add(2, WithJobId("second"), WithJobKey("key"))
+
+		// the second job waits for the key longer than the visibility timeout
+		time.Sleep(150 * time.Millisecond)
+		close(release)
+
+		_, err := first.Result()
+		assert.NoError(t, err)
+		_, err = second.Result()
+		assert.NoError(t, err)
+
+		assert.Equal(t, int32(2), mq.deliveries.Load(), "the parked job should not be redelivered")
+		assert.Eventually(t, func() bool {
+			return mq.Len() == 0 && mq.Inflight() == 0
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("lease is clamped to the minimum", func(t *testing.T) {
+		c := newConfig()
+		WithLease(time.Nanosecond)(&c)
+		assert.Equal(t, minLease, c.Lease)
+
+		WithLease(-time.Second)(&c)
+		assert.Zero(t, c.Lease)
+	})
+}
+
+// countingDeliveryQueue counts the deliveries of the memory queue
+type countingDeliveryQueue struct {
+	*MemoryQueue
+	deliveries atomic.Int32
+}
+
+func (q *countingDeliveryQueue) DequeueWithAckId() (any, bool, string) {
+	v, ok, ackId := q.MemoryQueue.DequeueWithAckId()
+	if ok {
+		q.deliveries.Add(1)
+	}
+
+	return v, ok, ackId
+}
@@ -19,6 +19,23 @@ func NewWorker[T, R any](wf WorkerFunc[T, R], config ...any) IWorkerBinder[T, R]
 	return newQueues(newWorker[T, R](wf, config...))
 }
 
+// NewBatchWorker creates a worker those function processes a batch of jobs at once, e.g. a bulk insert.
+// The jobs being processed are collected into a batch of up to WithBatchSize jobs, and the function is called once
+// for the batch as soon as it's full, its WithBatchLinger is over or no more pending job can join it.
+// The results are sent back to each job in the same order, and every job is retried, timed out or cancelled on its own.
+//
+// The concurrency is the number of the jobs processed at once, so it's raised to the batch size at least.
+//
+// Example:
+//
+//	worker := NewBatchWorker(func(rows []Row) ([]int64, error) {
+//	    return db.InsertRows(rows)
+//	}, WithBatchSize(500), WithBatchLinger(50*time.Millisecond))
+//	queue := worker.BindQueue()
+func NewBatchWorker[T, R any](wf BatchWorkerFunc[T, R], config ...any) IWorkerBinder[T, R] {
+	return newQueues(newWorker[T, R](wf, config...))
+}
+
 // NewErrWorker creates a worker for operations that only return errors (no result value).
 // This is useful for operations where you only care about success/failure status.
 // Like NewWorker, it can be bound to standard, priority, and persistent queue types.
@@ -61,3 +78,35 @@ func NewErrWorker[T any](wf WorkerErrFunc[T], config ...any) IWorkerBinder[T, an
 func NewVoidWorker[T any](wf VoidWorkerFunc[T], config ...any) IVoidWorkerBinder[T] {
 	return newVoidQueues(newWorker[T, any](wf, config...))
 }
+
+// NewWorkerCtx creates a worker like NewWorker, but the worker function receives a context
+// that is cancelled when the job is cancelled or times out, the job deadline is exceeded or the worker is stopped.
+// It's useful for long running operations (e.g. HTTP requests) those need to be interrupted.
+// It's a separate constructor, since the types of a function those might take a context or not can't be inferred.
+//
+// Example:
+//
+//	worker := NewWorkerCtx(func(ctx context.Context, url string) (string, error) {
+//	    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//	    if err != nil {
+//	        return "", err
+//	    }
+//	    ...
+//	}, 4)
+//	queue := worker.BindQueue()
+func NewWorkerCtx[T, R any](wf WorkerCtxFunc[T, R], config ...any) IWorkerBinder[T, R] {
+	return newQueues(newWorker[T, R](wf, config...))
+}
+
+// NewErrWorkerCtx creates a worker like NewErrWorker, but the worker function receives a context
+// that is cancelled when the job is cancelled or times out, the job deadline is exceeded or the worker is stopped.
+func NewErrWorkerCtx[T any](wf WorkerErrCtxFunc[T], config ...any) IWorkerBinder[T, any] {
+	return newQueues(newWorker[T, any](wf, config...))
+}
+
+// NewVoidWorkerCtx creates a worker like NewVoidWorker, but the worker function receives a context
+// that is cancelled when the job is cancelled or times out, the job deadline is exceeded or the worker is stopped.
+// Like NewVoidWorker, it can be bound to distributed queues as well.
+func NewVoidWorkerCtx[T any](wf VoidWorkerCtxFunc[T], config ...any) IVoidWorkerBinder[T] {
+	return newVoidQueues(newWorker[T, any](wf, config...))
+}
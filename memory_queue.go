@@ -0,0 +1,232 @@
+package varmq
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultVisibilityTimeout is the visibility timeout of a memory queue those is given none
+const defaultVisibilityTimeout = 30 * time.Second
+
+// MemoryQueue is an in-memory IDistributedQueue those redelivers the items those are not acknowledged
+// within the visibility timeout, e.g. once the consumer has crashed. It implements INackable and ILeaseExtendable,
+// so it serves as the reference of their semantics, e.g. for tests or a single process.
+type MemoryQueue struct {
+	items       []any
+	inflight    map[string]*delivery
+	visibility  time.Duration
+	nextAck     uint64
+	subscribers []func(action string)
+	mx          sync.Mutex
+}
+
+// delivery is a dequeued item those is not acknowledged yet
+type delivery struct {
+	item     any
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// NewMemoryQueue creates an empty memory queue with the given visibility timeout, 30 seconds if it's not positive.
+func NewMemoryQueue(visibility time.Duration) *MemoryQueue {
+	if visibility <= 0 {
+		visibility = defaultVisibilityTimeout
+	}
+
+	return &MemoryQueue{
+		inflight:   make(map[string]*delivery),
+		visibility: visibility,
+	}
+}
+
+func (q *MemoryQueue) Len() int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	return len(q.items)
+}
+
+// Inflight returns the number of the dequeued items those are not acknowledged yet.
+func (q *MemoryQueue) Inflight() int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	return len(q.inflight)
+}
+
+func (q *MemoryQueue) Values() []any {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	return slices.Clone(q.items)
+}
+
+func (q *MemoryQueue) Enqueue(item any) bool {
+	q.mx.Lock()
+	q.items = append(q.items, item)
+	q.mx.Unlock()
+
+	q.notify("enqueued")
+
+	return true
+}
+
+// Dequeue dequeues an item those needs no acknowledgement.
+func (q *MemoryQueue) Dequeue() (any, bool) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	return q.pop()
+}
+
+// pop removes the first item, it must be called with the lock held
+func (q *MemoryQueue) pop() (any, bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+
+	item := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+
+	return item, true
+}
+
+// DequeueWithAckId dequeues an item those is redelivered unless it's acknowledged within the visibility timeout.
+func (q *MemoryQueue) DequeueWithAckId() (any, bool, string) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	item, ok := q.pop()
+	if !ok {
+		return nil, false, ""
+	}
+
+	q.nextAck++
+	ackId := strconv.FormatUint(q.nextAck, 10)
+
+	q.inflight[ackId] = &delivery{
+		item:     item,
+		deadline: time.Now().Add(q.visibility),
+		timer:    time.AfterFunc(q.visibility, func() { q.expire(ackId) }),
+	}
+
+	return item, true, ackId
+}
+
+// expire redelivers the item those lease is over, before the other items
+func (q *MemoryQueue) expire(ackId string) {
+	q.mx.Lock()
+
+	d, ok := q.inflight[ackId]
+
+	// the lease might be extended while the timer was firing
+	if !ok || time.Now().Before(d.deadline) {
+		q.mx.Unlock()
+		return
+	}
+
+	delete(q.inflight, ackId)
+	q.items = slices.Insert(q.items, 0, d.item)
+	q.mx.Unlock()
+
+	q.notify("enqueued")
+}
+
+// settle removes the delivery and stops its timer, it returns false if it's already settled or redelivered
+func (q *MemoryQueue) settle(ackId string) (any, bool) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	d, ok := q.inflight[ackId]
+	if !ok {
+		return nil, false
+	}
+
+	d.timer.Stop()
+	delete(q.inflight, ackId)
+
+	return d.item, true
+}
+
+func (q *MemoryQueue) Acknowledge(ackID string) bool {
+	_, ok := q.settle(ackID)
+	return ok
+}
+
+// Nack redelivers the item after the delay if requeue is true, otherwise it's discarded.
+func (q *MemoryQueue) Nack(ackID string, requeue bool, delay time.Duration) bool {
+	item, ok := q.settle(ackID)
+	if !ok {
+		return false
+	}
+
+	if !requeue {
+		return true
+	}
+
+	if delay > 0 {
+		time.AfterFunc(delay, func() { q.Enqueue(item) })
+	} else {
+		q.Enqueue(item)
+	}
+
+	return true
+}
+
+func (q *MemoryQueue) ExtendLease(ackID string, d time.Duration) bool {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	delivery, ok := q.inflight[ackID]
+	if !ok {
+		return false
+	}
+
+	delivery.deadline = time.Now().Add(d)
+	delivery.timer.Reset(d)
+
+	return true
+}
+
+func (q *MemoryQueue) Subscribe(fn func(action string)) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	q.subscribers = append(q.subscribers, fn)
+}
+
+func (q *MemoryQueue) notify(action string) {
+	q.mx.Lock()
+	subscribers := slices.Clone(q.subscribers)
+	q.mx.Unlock()
+
+	for _, fn := range subscribers {
+		fn(action)
+	}
+}
+
+// Purge removes the pending items, the dequeued ones those are not acknowledged yet are kept.
+func (q *MemoryQueue) Purge() {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	q.items = nil
+}
+
+// Close removes all the items, the dequeued ones those are not acknowledged yet are not redelivered anymore.
+func (q *MemoryQueue) Close() error {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	for ackId, d := range q.inflight {
+		d.timer.Stop()
+		delete(q.inflight, ackId)
+	}
+
+	q.items = nil
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package varmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryQueue(t *testing.T) {
+	t.Run("acknowledged item is not redelivered", func(t *testing.T) {
+		q := NewMemoryQueue(10 * time.Millisecond)
+		defer q.Close()
+
+		q.Enqueue("a")
+
+		item, ok, ackId := q.DequeueWithAckId()
+		assert.True(t, ok)
+		assert.Equal(t, "a", item)
+		assert.Equal(t, 1, q.Inflight())
+
+		assert.True(t, q.Acknowledge(ackId))
+		assert.False(t, q.Acknowledge(ackId))
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, 0, q.Len())
+		assert.Equal(t, 0, q.Inflight())
+	})
+
+	t.Run("expired item is redelivered first", func(t *testing.T) {
+		q := NewMemoryQueue(10 * time.Millisecond)
+		defer q.Close()
+
+		actions := make(chan string, 3)
+		q.Subscribe(func(action string) {
+			actions <- action
+		})
+
+		q.Enqueue("a")
+		q.Enqueue("b")
+		<-actions
+		<-actions
+
+		_, _, ackId := q.DequeueWithAckId()
+
+		assert.Equal(t, "enqueued", <-actions, "the redelivery should be notified")
+		assert.Equal(t, []any{"a", "b"}, q.Values())
+		assert.False(t, q.Acknowledge(ackId), "the lease should be lost")
+	})
+
+	t.Run("extended lease keeps the item", func(t *testing.T) {
+		q := NewMemoryQueue(20 * time.Millisecond)
+		defer q.Close()
+
+		q.Enqueue("a")
+		_, _, ackId := q.DequeueWithAckId()
+
+		for range 4 {
+			time.Sleep(10 * time.Millisecond)
+			assert.True(t, q.ExtendLease(ackId, 20*time.Millisecond))
+		}
+
+		assert.Equal(t, 0, q.Len())
+		assert.True(t, q.Acknowledge(ackId))
+		assert.False(t, q.ExtendLease(ackId, time.Second))
+	})
+
+	t.Run("nacked item is requeued or discarded", func(t *testing.T) {
+		q := NewMemoryQueue(time.Minute)
+		defer q.Close()
+
+		q.Enqueue("a")
+		q.Enqueue("b")
+
+		_, _, first := q.DequeueWithAckId()
+		_, _, second := q.DequeueWithAckId()
+
+		assert.True(t, q.Nack(first, false, 0))
+		assert.True(t, q.Nack(second, true, 10*time.Millisecond))
+		assert.False(t, q.Nack(second, true, 0))
+
+		assert.Equal(t, 0, q.Len())
+		assert.Eventually(t, func() bool {
+			return q.Len() == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, []any{"b"}, q.Values())
+	})
+
+	t.Run("item dequeued without ack id needs no acknowledgement", func(t *testing.T) {
+		q := NewMemoryQueue(0)
+		defer q.Close()
+
+		q.Enqueue("a")
+
+		item, ok := q.Dequeue()
+		assert.True(t, ok)
+		assert.Equal(t, "a", item)
+		assert.Equal(t, 0, q.Inflight())
+	})
+}
@@ -0,0 +1,340 @@
+package varmq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histograms.
+var latencyBuckets = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+var errMetricsExists = errors.New("metrics source already exists with the same name")
+
+// Metrics is a snapshot of the metrics of a worker.
+type Metrics struct {
+	// Enqueued is the number of jobs those are accepted by the queue.
+	Enqueued uint64
+	// Started is the number of the started attempts, retries included.
+	Started uint64
+	// Succeeded is the number of the succeeded attempts.
+	Succeeded uint64
+	// Failed is the number of the attempts those are failed with an error, including timeouts and cancellations.
+	Failed uint64
+	// Panicked is the number of the attempts those panicked.
+	Panicked uint64
+	// Retried is the number of the failed jobs those are scheduled to be retried.
+	Retried uint64
+	// Unparsable is the number of the dequeued payloads those couldn't be parsed to jobs, see WithQuarantine.
+	Unparsable uint64
+
+	// Pending is the number of the jobs waiting in the queue, delayed ones included.
+	Pending int
+	// Processing is the number of the jobs being processed.
+	Processing int
+	// PoolSize is the concurrency of the worker.
+	PoolSize int
+	// IdleWorkers is the number of the idle workers in the pool.
+	IdleWorkers int
+
+	// QueueWait is the distribution of the waiting time of the jobs in the queue before they're started.
+	QueueWait Histogram
+	// ProcessingTime is the distribution of the running time of the attempts.
+	ProcessingTime Histogram
+}
+
+// Histogram is a snapshot of a latency distribution.
+type Histogram struct {
+	// Buckets are the cumulative counts of the observations, ordered by their upper bounds.
+	Buckets []Bucket
+	Count   uint64
+	Sum     time.Duration
+}
+
+// Bucket is the number of observations those are less than or equal to the upper bound.
+type Bucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// histogram records observations into the buckets lock-free
+type histogram struct {
+	// the last count is for the observations above all the bounds
+	counts [len(latencyBuckets) + 1]atomic.Uint64
+	sum    atomic.Int64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.Search(len(latencyBuckets), func(i int) bool {
+		return d <= latencyBuckets[i]
+	})
+
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Buckets: make([]Bucket, len(latencyBuckets)),
+		Sum:     time.Duration(h.sum.Load()),
+	}
+
+	for i, bound := range latencyBuckets {
+		s.Count += h.counts[i].Load()
+		s.Buckets[i] = Bucket{UpperBound: bound, Count: s.Count}
+	}
+
+	s.Count += h.counts[len(latencyBuckets)].Load()
+
+	return s
+}
+
+// workerMetrics holds the counters and histograms of a worker, they're updated by the lifecycle events
+type workerMetrics struct {
+	enqueued       atomic.Uint64
+	started        atomic.Uint64
+	succeeded      atomic.Uint64
+	failed         atomic.Uint64
+	panicked       atomic.Uint64
+	retried        atomic.Uint64
+	unparsable     atomic.Uint64
+	queueWait      histogram
+	processingTime histogram
+	// timed is set once the metrics are read, the latencies are not measured until then
+	timed atomic.Bool
+}
+
+func (m *workerMetrics) observeProcessing(timed bool, d time.Duration) {
+	if timed {
+		m.processingTime.observe(d)
+	}
+}
+
+func (m *workerMetrics) observe(t EventType, d time.Duration) {
+	timed := m.timed.Load()
+
+	switch t {
+	case EventEnqueued:
+		m.enqueued.Add(1)
+	case EventStarted:
+		m.started.Add(1)
+		if timed {
+			m.queueWait.observe(d)
+		}
+	case EventSucceeded:
+		m.succeeded.Add(1)
+		m.observeProcessing(timed, d)
+	case EventFailed:
+		m.failed.Add(1)
+		m.observeProcessing(timed, d)
+	case EventPanicked:
+		m.panicked.Add(1)
+		m.observeProcessing(timed, d)
+	case EventRetrying:
+		m.retried.Add(1)
+	}
+}
+
+// measured reports whether the durations of the jobs are needed, by the metrics or the event hooks
+func (w *worker[T, R]) measured() bool {
+	return w.metrics.timed.Load() || w.hooks.active.Load()
+}
+
+// since returns the time elapsed since t, or 0 if the durations are not measured
+func (w *worker[T, R]) since(t time.Time) time.Duration {
+	if t.IsZero() || !w.measured() {
+		return 0
+	}
+
+	return time.Since(t)
+}
+
+// now returns the current time, or the zero time if the durations are not measured
+func (w *worker[T, R]) now() time.Time {
+	if !w.measured() {
+		return time.Time{}
+	}
+
+	return time.Now()
+}
+
+// Metrics returns a snapshot of the metrics of the worker.
+// The latency histograms are recorded once the metrics are read for the first time,
+// so the jobs of a worker those metrics are never read are not measured.
+func (w *worker[T, R]) Metrics() Metrics {
+	w.metrics.timed.Store(true)
+
+	return Metrics{
+		Enqueued:       w.metrics.enqueued.Load(),
+		Started:        w.metrics.started.Load(),
+		Succeeded:      w.metrics.succeeded.Load(),
+		Failed:         w.metrics.failed.Load(),
+		Panicked:       w.metrics.panicked.Load(),
+		Retried:        w.metrics.retried.Load(),
+		Unparsable:     w.metrics.unparsable.Load(),
+		Pending:        w.numPending(),
+		Processing:     w.NumProcessing(),
+		PoolSize:       w.NumConcurrency(),
+		IdleWorkers:    w.NumIdleWorkers(),
+		QueueWait:      w.metrics.queueWait.snapshot(),
+		ProcessingTime: w.metrics.processingTime.snapshot(),
+	}
+}
+
+// MetricsSource is anything those provides a metrics snapshot, e.g. a Worker.
+type MetricsSource interface {
+	Metrics() Metrics
+}
+
+// MetricsRegistry collects the metrics of the registered workers by their names,
+// and serves them over HTTP in the Prometheus text exposition format.
+type MetricsRegistry struct {
+	sources map[string]MetricsSource
+	mx      sync.RWMutex
+}
+
+// NewMetricsRegistry creates an empty metrics registry.
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{
+		sources: make(map[string]MetricsSource),
+	}
+}
+
+// Register adds the source with the given name, which is used as the value of the worker label.
+// It returns an error if a source with the same name is already registered.
+func (r *MetricsRegistry) Register(name string, source MetricsSource) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if _, ok := r.sources[name]; ok {
+		return errMetricsExists
+	}
+
+	r.sources[name] = source
+	// reading the source once makes a worker record its latencies from now on, see Worker.Metrics
+	source.Metrics()
+
+	return nil
+}
+
+// Unregister removes the source with the given name, and returns whether it's removed.
+func (r *MetricsRegistry) Unregister(name string) bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if _, ok := r.sources[name]; !ok {
+		return false
+	}
+
+	delete(r.sources, name)
+	return true
+}
+
+// ServeHTTP writes the metrics of all the registered sources in the Prometheus text format.
+func (r *MetricsRegistry) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(rw)
+}
+
+// WriteTo writes the metrics of all the registered sources in the Prometheus text format.
+func (r *MetricsRegistry) WriteTo(out io.Writer) (int64, error) {
+	r.mx.RLock()
+	names := make([]string, 0, len(r.sources))
+	snapshots := make(map[string]Metrics, len(r.sources))
+
+	for name, source := range r.sources {
+		names = append(names, name)
+		snapshots[name] = source.Metrics()
+	}
+	r.mx.RUnlock()
+
+	slices.Sort(names)
+
+	var b strings.Builder
+
+	writeFamily := func(name, typ, help string, value func(m Metrics) string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+
+		for _, worker := range names {
+			fmt.Fprintf(&b, "%s{worker=\"%s\"} %s\n", name, escapeLabel(worker), value(snapshots[worker]))
+		}
+	}
+
+	counter := func(name, help string, value func(m Metrics) uint64) {
+		writeFamily(name, "counter", help, func(m Metrics) string {
+			return strconv.FormatUint(value(m), 10)
+		})
+	}
+
+	gauge := func(name, help string, value func(m Metrics) int) {
+		writeFamily(name, "gauge", help, func(m Metrics) string {
+			return strconv.Itoa(value(m))
+		})
+	}
+
+	hist := func(name, help string, value func(m Metrics) Histogram) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+		for _, worker := range names {
+			h := value(snapshots[worker])
+			label := escapeLabel(worker)
+
+			for _, bucket := range h.Buckets {
+				fmt.Fprintf(&b, "%s_bucket{worker=\"%s\",le=\"%s\"} %d\n", name, label, formatSeconds(bucket.UpperBound), bucket.Count)
+			}
+
+			fmt.Fprintf(&b, "%s_bucket{worker=\"%s\",le=\"+Inf\"} %d\n", name, label, h.Count)
+			fmt.Fprintf(&b, "%s_sum{worker=\"%s\"} %s\n", name, label, formatSeconds(h.Sum))
+			fmt.Fprintf(&b, "%s_count{worker=\"%s\"} %d\n", name, label, h.Count)
+		}
+	}
+
+	counter("varmq_jobs_enqueued_total", "Number of jobs enqueued.", func(m Metrics) uint64 { return m.Enqueued })
+	counter("varmq_jobs_started_total", "Number of job attempts started.", func(m Metrics) uint64 { return m.Started })
+	counter("varmq_jobs_succeeded_total", "Number of job attempts succeeded.", func(m Metrics) uint64 { return m.Succeeded })
+	counter("varmq_jobs_failed_total", "Number of job attempts failed with an error.", func(m Metrics) uint64 { return m.Failed })
+	counter("varmq_jobs_panicked_total", "Number of job attempts panicked.", func(m Metrics) uint64 { return m.Panicked })
+	counter("varmq_jobs_retried_total", "Number of failed jobs scheduled to be retried.", func(m Metrics) uint64 { return m.Retried })
+	counter("varmq_jobs_unparsable_total", "Number of dequeued payloads those couldn't be parsed to jobs.", func(m Metrics) uint64 { return m.Unparsable })
+	gauge("varmq_jobs_pending", "Number of jobs waiting in the queue.", func(m Metrics) int { return m.Pending })
+	gauge("varmq_jobs_processing", "Number of jobs being processed.", func(m Metrics) int { return m.Processing })
+	gauge("varmq_pool_size", "Concurrency of the worker.", func(m Metrics) int { return m.PoolSize })
+	gauge("varmq_idle_workers", "Number of idle workers in the pool.", func(m Metrics) int { return m.IdleWorkers })
+	hist("varmq_job_queue_wait_seconds", "Time jobs waited in the queue before being started.", func(m Metrics) Histogram { return m.QueueWait })
+	hist("varmq_job_processing_seconds", "Running time of job attempts.", func(m Metrics) Histogram { return m.ProcessingTime })
+
+	n, err := io.WriteString(out, b.String())
+	return int64(n), err
+}
+
+// formatSeconds formats the duration as seconds, the base unit of Prometheus
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes the label value as the exposition format requires
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
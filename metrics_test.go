@@ -0,0 +1,187 @@
+package varmq
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("counts the lifecycle of the jobs", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		w := NewWorker(func(data int) (int, error) {
+			switch data {
+			case 1:
+				return 0, errFailed
+			case 2:
+				panic("boom")
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			return data, nil
+		}, WithConcurrency(2))
+
+		closed := make(chan struct{}, 4)
+		w.OnClose(func(Event) { closed <- struct{}{} })
+
+		// the latencies are recorded once the metrics are read
+		w.Metrics()
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		q.Add(1, WithRetry(2, ConstantBackoff(0)))
+		q.Add(2)
+		q.Add(3)
+		q.Add(4)
+
+		for range 4 {
+			<-closed
+		}
+
+		m := w.Metrics()
+		assert.Equal(t, uint64(4), m.Enqueued)
+		assert.Equal(t, uint64(5), m.Started, "the retry should be counted as a new attempt")
+		assert.Equal(t, uint64(2), m.Succeeded)
+		assert.Equal(t, uint64(2), m.Failed)
+		assert.Equal(t, uint64(1), m.Panicked)
+		assert.Equal(t, uint64(1), m.Retried)
+		assert.Equal(t, 0, m.Pending)
+		assert.Equal(t, 2, m.PoolSize)
+
+		assert.Equal(t, uint64(5), m.QueueWait.Count)
+		assert.Equal(t, uint64(5), m.ProcessingTime.Count)
+		assert.GreaterOrEqual(t, m.ProcessingTime.Sum, 20*time.Millisecond)
+		assert.Len(t, m.ProcessingTime.Buckets, len(latencyBuckets))
+	})
+
+	t.Run("doesn't count the rejected jobs as enqueued", func(t *testing.T) {
+		w := NewVoidWorker(func(_ int) {}, WithMaxPending(1))
+		q := w.WithPersistentQueue(rejectingPersistentQueue{newMockPersistentQueue()})
+		defer q.Close()
+
+		_, ok := q.Add(1, WithJobId("job-1"))
+		assert.False(t, ok)
+
+		assert.Zero(t, w.Metrics().Enqueued)
+	})
+
+	t.Run("counts the waiting time of the jobs", func(t *testing.T) {
+		w := NewVoidWorker(func(_ int) {})
+
+		started := make(chan Event, 1)
+		w.OnStart(func(e Event) { started <- e })
+
+		q := w.BindQueue()
+		defer q.Close()
+		w.Pause()
+
+		q.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, 1, w.Metrics().Pending)
+		assert.NoError(t, w.Resume())
+
+		e := <-started
+		assert.GreaterOrEqual(t, e.Duration, 30*time.Millisecond)
+
+		h := w.Metrics().QueueWait
+		assert.Equal(t, uint64(1), h.Count)
+		assert.Equal(t, uint64(0), h.Buckets[2].Count, "the job should wait longer than 25ms")
+		assert.GreaterOrEqual(t, h.Sum, 30*time.Millisecond)
+	})
+
+	t.Run("latencies are not measured until the metrics are read", func(t *testing.T) {
+		w := NewVoidWorker(func(_ int) {})
+		q := w.BindQueue()
+		defer q.Close()
+
+		q.Add(1)
+		q.WaitUntilFinished()
+
+		m := w.Metrics()
+		assert.Equal(t, uint64(1), m.Succeeded, "the jobs should be counted anyway")
+		assert.Zero(t, m.ProcessingTime.Count)
+
+		q.Add(2)
+		q.WaitUntilFinished()
+		assert.Equal(t, uint64(1), w.Metrics().ProcessingTime.Count)
+	})
+
+	t.Run("histogram buckets are cumulative", func(t *testing.T) {
+		var h histogram
+		h.observe(time.Millisecond)
+		h.observe(time.Second)
+		h.observe(time.Minute)
+
+		s := h.snapshot()
+		assert.Equal(t, uint64(3), s.Count)
+		assert.Equal(t, time.Minute+time.Second+time.Millisecond, s.Sum)
+		assert.Equal(t, uint64(1), s.Buckets[0].Count)
+		assert.Equal(t, time.Second, s.Buckets[7].UpperBound)
+		assert.Equal(t, uint64(2), s.Buckets[7].Count)
+		assert.Equal(t, uint64(2), s.Buckets[len(s.Buckets)-1].Count)
+	})
+}
+
+func TestMetricsRegistry(t *testing.T) {
+	t.Run("register and unregister", func(t *testing.T) {
+		r := NewMetricsRegistry()
+		w := newWorker[int, int](func(data int) (int, error) { return data, nil })
+
+		assert.NoError(t, r.Register("a", w))
+		assert.ErrorIs(t, r.Register("a", w), errMetricsExists)
+		assert.True(t, r.Unregister("a"))
+		assert.False(t, r.Unregister("a"))
+	})
+
+	t.Run("serves the prometheus text format", func(t *testing.T) {
+		w := NewWorker(func(data int) (int, error) { return data, nil })
+		q := w.BindQueue()
+		defer q.Close()
+
+		r := NewMetricsRegistry()
+		r.Register("emails", w)
+		r.Register(`we"ird`, newWorker[int, int](func(data int) (int, error) { return data, nil }))
+
+		job, _ := q.Add(1)
+		job.Result()
+		q.WaitUntilFinished()
+
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+
+		body := rec.Body.String()
+		for _, line := range []string{
+			"# TYPE varmq_jobs_enqueued_total counter",
+			`varmq_jobs_enqueued_total{worker="emails"} 1`,
+			`varmq_jobs_succeeded_total{worker="emails"} 1`,
+			`varmq_jobs_enqueued_total{worker="we\"ird"} 0`,
+			"# TYPE varmq_pool_size gauge",
+			`varmq_pool_size{worker="emails"} 1`,
+			"# TYPE varmq_job_processing_seconds histogram",
+			`varmq_job_processing_seconds_bucket{worker="emails",le="10"} 1`,
+			`varmq_job_processing_seconds_bucket{worker="emails",le="+Inf"} 1`,
+			`varmq_job_processing_seconds_count{worker="emails"} 1`,
+		} {
+			assert.Contains(t, body, line+"\n")
+		}
+
+		// the workers are sorted by name
+		assert.Less(t, strings.Index(body, `worker="emails"`), strings.Index(body, `worker="we\"ird"`))
+	})
+}
+
+// rejectingPersistentQueue is a persistent queue those fails to enqueue any item
+type rejectingPersistentQueue struct {
+	*mockPersistentQueue
+}
+
+func (q rejectingPersistentQueue) Enqueue(item any) bool {
+	return false
+}
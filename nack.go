@@ -0,0 +1,39 @@
+package varmq
+
+import "time"
+
+// WithNackPolicy sets how the jobs those finally fail are negatively acknowledged, if the queue implements INackable.
+// They're redelivered by the queue after the delay if requeue is true, otherwise they're discarded or dead lettered by it.
+// Default is no requeue, since a redelivered job runs all of its attempts again, so a job those always fails would be
+// redelivered forever. Set a delay to requeue them, and let the queue cap the redeliveries.
+// The failed jobs moved to the dead letter queue of the worker are acknowledged instead.
+func WithNackPolicy(requeue bool, delay time.Duration) ConfigFunc {
+	return func(c *configs) {
+		c.NackRequeue = requeue
+		c.NackDelay = max(delay, 0)
+	}
+}
+
+// nackJob hands the delivery of the failed job back to the queue if it supports negative acknowledgement,
+// unless the job is already moved to the dead letter queue.
+func (w *worker[T, R]) nackJob(j iJob[T, R], deadLettered bool) {
+	q, ok := w.Queue.(INackable)
+	ackId := j.AckId()
+
+	if !ok || ackId == "" || deadLettered {
+		return
+	}
+
+	requeued := q.Nack(ackId, w.configs.NackRequeue, w.configs.NackDelay) && w.configs.NackRequeue
+	w.leases.release(ackId)
+
+	// the delivery must not be acknowledged once the job is closed, even if the nack failed,
+	// so the queue can redeliver it, e.g. once its visibility timeout is over
+	j.SetAckId("")
+
+	if requeued {
+		// the redelivery is a new job, so the closed one must not be found by its id
+		w.Cache.Delete(j.ID())
+		w.notifyToPullNextJobs()
+	}
+}
@@ -0,0 +1,165 @@
+package varmq
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nack is a negative acknowledgement received by mockNackableQueue
+type nack struct {
+	ackId   string
+	requeue bool
+	delay   time.Duration
+}
+
+// mockNackableQueue is a mockPersistentQueue those implements INackable, requeued items are redelivered right away
+type mockNackableQueue struct {
+	*mockPersistentQueue
+	nacks []nack
+	nmx   sync.Mutex
+}
+
+func newMockNackableQueue() *mockNackableQueue {
+	return &mockNackableQueue{mockPersistentQueue: newMockPersistentQueue()}
+}
+
+func (q *mockNackableQueue) Nack(ackID string, requeue bool, delay time.Duration) bool {
+	q.mx.Lock()
+	item, ok := q.unacked[ackID]
+	delete(q.unacked, ackID)
+
+	if ok && requeue {
+		q.items = append(q.items, item)
+	}
+	q.mx.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	q.nmx.Lock()
+	q.nacks = append(q.nacks, nack{ackId: ackID, requeue: requeue, delay: delay})
+	q.nmx.Unlock()
+
+	return true
+}
+
+func (q *mockNackableQueue) Nacks() []nack {
+	q.nmx.Lock()
+	defer q.nmx.Unlock()
+
+	return append([]nack(nil), q.nacks...)
+}
+
+func TestNack(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	t.Run("failed job is not requeued by default", func(t *testing.T) {
+		mq := newMockNackableQueue()
+		var calls atomic.Int32
+
+		q := NewErrWorker(func(data int) error {
+			calls.Add(1)
+			return errFailed
+		}).WithPersistentQueue(mq)
+		defer q.Close()
+
+		_, ok := q.Add(1, WithJobId("job"))
+		assert.True(t, ok)
+
+		assert.Eventually(t, func() bool {
+			return len(mq.Nacks()) == 1
+		}, time.Second, time.Millisecond)
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, int32(1), calls.Load(), "the failed job should not be redelivered")
+		assert.Equal(t, []nack{{ackId: "ack-1", requeue: false}}, mq.Nacks())
+		assert.Equal(t, 0, mq.Len())
+	})
+
+	t.Run("failed job is requeued by the policy", func(t *testing.T) {
+		mq := newMockNackableQueue()
+		var calls atomic.Int32
+
+		q := NewErrWorker(func(data int) error {
+			if calls.Add(1) == 1 {
+				return errFailed
+			}
+
+			return nil
+		}, WithNackPolicy(true, time.Second)).WithPersistentQueue(mq)
+		defer q.Close()
+
+		_, ok := q.Add(1, WithJobId("job"))
+		assert.True(t, ok)
+
+		assert.Eventually(t, func() bool {
+			return calls.Load() == 2 && mq.Unacked() == 0
+		}, time.Second, time.Millisecond)
+
+		assert.Equal(t, []nack{{ackId: "ack-1", requeue: true, delay: time.Second}}, mq.Nacks())
+		assert.Equal(t, []string{"ack-2"}, mq.acked, "only the redelivery should be acknowledged")
+	})
+
+	t.Run("panicked job is nacked by the policy", func(t *testing.T) {
+		mq := newMockNackableQueue()
+
+		q := NewVoidWorker(func(data int) {
+			panic("boom")
+		}, WithNackPolicy(false, time.Minute)).WithPersistentQueue(mq)
+		defer q.Close()
+
+		_, ok := q.Add(1, WithJobId("job"))
+		assert.True(t, ok)
+
+		assert.Eventually(t, func() bool {
+			return len(mq.Nacks()) == 1
+		}, time.Second, time.Millisecond)
+
+		assert.Equal(t, []nack{{ackId: "ack-1", requeue: false, delay: time.Minute}}, mq.Nacks())
+		assert.Equal(t, 0, mq.Len())
+		assert.Equal(t, 0, mq.Unacked())
+	})
+
+	t.Run("dead lettered job is acknowledged", func(t *testing.T) {
+		mq := newMockNackableQueue()
+		dlq := newMockPersistentQueue()
+
+		q := NewErrWorker(func(data int) error {
+			return errFailed
+		}, WithDeadLetterQueue(dlq)).WithPersistentQueue(mq)
+		defer q.Close()
+
+		_, ok := q.Add(1, WithJobId("job"))
+		assert.True(t, ok)
+
+		assert.Eventually(t, func() bool {
+			return dlq.Len() == 1 && mq.Unacked() == 0
+		}, time.Second, time.Millisecond)
+
+		assert.Empty(t, mq.Nacks())
+	})
+
+	t.Run("succeeded job is acknowledged", func(t *testing.T) {
+		mq := newMockNackableQueue()
+
+		q := NewErrWorker(func(data int) error {
+			return nil
+		}).WithPersistentQueue(mq)
+		defer q.Close()
+
+		_, ok := q.Add(1, WithJobId("job"))
+		assert.True(t, ok)
+
+		assert.Eventually(t, func() bool {
+			return mq.Unacked() == 0 && mq.Len() == 0
+		}, time.Second, time.Millisecond)
+
+		assert.Empty(t, mq.Nacks())
+	})
+}
@@ -0,0 +1,331 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// OverflowPolicy decides what happens when a job is added into a queue those already has the max pending jobs.
+type OverflowPolicy uint8
+
+const (
+	// OverflowReject rejects the new job with ErrQueueFull.
+	OverflowReject OverflowPolicy = iota
+	// OverflowBlock blocks Add until there is room for the new job.
+	OverflowBlock
+	// OverflowDropOldest drops the oldest pending job with ErrQueueFull to make room for the new job.
+	OverflowDropOldest
+	// OverflowDropLowestPriority drops the pending job with the lowest priority with ErrQueueFull to make room for the new job,
+	// only if its priority is lower than the new job's one, otherwise the new job is rejected.
+	OverflowDropLowestPriority
+)
+
+var errEnqueueFailed = errors.New("failed to enqueue the job")
+
+// WithMaxPending bounds the number of pending jobs of the queue, including the delayed ones.
+// When the queue is full, new jobs are handled by the overflow policy, see WithOverflowPolicy.
+// Default is 0, which means the queue is unbounded.
+func WithMaxPending(n int) ConfigFunc {
+	return func(c *configs) {
+		c.MaxPending = max(n, 0)
+	}
+}
+
+// WithOverflowPolicy sets what happens when a job is added into a full queue.
+// Default is OverflowReject.
+func WithOverflowPolicy(policy OverflowPolicy) ConfigFunc {
+	return func(c *configs) {
+		c.Overflow = policy
+	}
+}
+
+// spaceSignal broadcasts to the waiters when a pending job leaves the queue
+type spaceSignal struct {
+	ch chan struct{}
+	mx sync.Mutex
+}
+
+// wait returns a channel those is closed on the next broadcast
+func (s *spaceSignal) wait() <-chan struct{} {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.ch == nil {
+		s.ch = make(chan struct{})
+	}
+
+	return s.ch
+}
+
+func (s *spaceSignal) broadcast() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	// nobody is waiting if there is no channel
+	if s.ch != nil {
+		close(s.ch)
+		s.ch = nil
+	}
+}
+
+func (w *worker[T, R]) numPending() int {
+	return w.Queue.Len() + w.delayed.Len() + w.deps.Len() + w.keys.Len()
+}
+
+// notifySpace wakes up the jobs those are waiting for room in the queue
+func (w *worker[T, R]) notifySpace() {
+	if w.configs.MaxPending > 0 {
+		w.space.broadcast()
+	}
+}
+
+// noop is the release of an unbounded queue, a function literal in the generic methods would be allocated per job
+func noop() {}
+
+// addJob enqueues the job using the given function if there is room for it according to the overflow policy.
+// If the job can't be enqueued, it's finished with the reason, and the reason is returned.
+func (w *worker[T, R]) addJob(ctx context.Context, j iJob[T, R], wait bool, enqueue func() bool) error {
+	release := noop
+	err := w.checkDependency(j)
+
+	if err == nil {
+		release, err = w.reserve(ctx, j.Priority(), wait)
+	}
+
+	if err == nil {
+		// the delay starts once there is room for the job, before it's held or encoded by the enqueue function
+		j.admit()
+		held := w.holdJob(j)
+		ok := held || enqueue()
+
+		if ok && !held {
+			w.indexPendingJob(j)
+		}
+
+		release()
+
+		if ok {
+			// the worker might start the job in the meantime, then it's emitted by the worker before starting it
+			w.emitEnqueued(j)
+		} else {
+			err = errEnqueueFailed
+		}
+	}
+
+	if err != nil {
+		// the job is never accepted, so it's not announced as enqueued
+		j.announce(noop)
+		j.SaveAndSendError(err)
+		w.closeJob(j, err)
+	}
+
+	return err
+}
+
+// reserve waits or makes room for a new job with the given priority,
+// and returns a function to be called once the job is enqueued.
+// A job dropped to make room is finished by the returned function, once the admission lock is released.
+func (w *worker[T, R]) reserve(ctx context.Context, priority int, wait bool) (func(), error) {
+	limit := w.configs.MaxPending
+	if limit <= 0 {
+		return noop, nil
+	}
+
+	for {
+		// take the signal before checking, to not to miss the space freed in between
+		space := w.space.wait()
+
+		// hold the lock until the job is enqueued, to not to exceed the limit by concurrent adds
+		w.admissionMx.Lock()
+
+		if w.numPending() < limit {
+			return w.admissionMx.Unlock, nil
+		}
+
+		if wait {
+			w.admissionMx.Unlock()
+
+			select {
+			case <-space:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		finish, dropped := w.dropPendingJob(priority)
+		if dropped && w.numPending() < limit {
+			return func() {
+				w.admissionMx.Unlock()
+				finish()
+			}, nil
+		}
+
+		w.admissionMx.Unlock()
+
+		if dropped {
+			finish()
+		}
+
+		return nil, ErrQueueFull
+	}
+}
+
+// dropPendingJob drops a pending job according to the overflow policy to make room for a new job with the given priority.
+// It returns a function to finish the dropped job, those hooks must not run while the admission lock is held.
+// The jobs in the queue are dropped only if the queue can remove them, otherwise they'd still take the room.
+func (w *worker[T, R]) dropPendingJob(priority int) (func(), bool) {
+	var victim iJob[T, R]
+
+	for _, j := range w.pendingJobs() {
+		if j.IsClosed() || j.IsCancelled() {
+			continue
+		}
+
+		switch w.configs.Overflow {
+		case OverflowDropOldest:
+			if victim == nil || j.CreatedAt().Before(victim.CreatedAt()) {
+				victim = j
+			}
+		case OverflowDropLowestPriority:
+			if j.Priority() <= priority {
+				continue
+			}
+
+			// the newest one is dropped among the jobs with the same priority
+			if victim == nil || j.Priority() > victim.Priority() ||
+				(j.Priority() == victim.Priority() && j.CreatedAt().After(victim.CreatedAt())) {
+				victim = j
+			}
+		}
+	}
+
+	if victim == nil || !victim.drop() {
+		return nil, false
+	}
+
+	return w.removeCancelledJob(victim), true
+}
+
+// pendingJobs returns the jobs those are waiting in the queue, delayed, held for their parents or parked.
+// The jobs in the queue are left out if the queue can't remove them.
+func (w *worker[T, R]) pendingJobs() []iJob[T, R] {
+	jobs := append(w.delayed.Values(), w.deps.Values()...)
+	jobs = append(jobs, w.keys.Values()...)
+
+	if _, ok := w.Queue.(IRemovable); !ok {
+		return jobs
+	}
+
+	// the payloads of persistent queues are indexed, so they're not decoded on every add
+	if _, ok := w.Queue.(IAcknowledgeable); ok {
+		return append(jobs, w.pending.values(w.Queue.Len(), w.loadPendingJobs)...)
+	}
+
+	for _, v := range w.Queue.Values() {
+		if j, ok := v.(iJob[T, R]); ok {
+			jobs = append(jobs, j)
+		}
+	}
+
+	return jobs
+}
+
+// loadPendingJobs decodes the payloads of the queue to the jobs, those enqueued by this worker are taken from the cache
+func (w *worker[T, R]) loadPendingJobs() []iJob[T, R] {
+	values := w.Queue.Values()
+	jobs := make([]iJob[T, R], 0, len(values))
+
+	for _, v := range values {
+		data, ok := v.([]byte)
+		if !ok {
+			continue
+		}
+
+		j, err := parseToJob[T, R](data, w.configs.Codec)
+		if err != nil {
+			continue
+		}
+
+		if cachedJob, ok := w.Cache.Load(j.ID()); ok {
+			j = cachedJob.(iJob[T, R])
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs
+}
+
+// indexPendingJob indexes the job enqueued into a persistent queue, if the overflow policy drops the pending jobs
+func (w *worker[T, R]) indexPendingJob(j iJob[T, R]) {
+	if w.configs.MaxPending <= 0 {
+		return
+	}
+
+	switch w.configs.Overflow {
+	case OverflowDropOldest, OverflowDropLowestPriority:
+	default:
+		return
+	}
+
+	if _, ok := w.Queue.(IAcknowledgeable); ok {
+		w.pending.add(j)
+	}
+}
+
+// pendingIndex holds the jobs in a persistent queue by their ids, so the drop policies can pick a victim
+// without decoding every payload of the queue. It's rebuilt from the queue once it doesn't match the length
+// of the queue, e.g. the payloads enqueued before a restart or redelivered by the queue.
+type pendingIndex[T, R any] struct {
+	jobs map[string]iJob[T, R]
+	mx   sync.Mutex
+}
+
+func (p *pendingIndex[T, R]) add(j iJob[T, R]) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.jobs == nil {
+		p.jobs = make(map[string]iJob[T, R])
+	}
+
+	p.jobs[j.ID()] = j
+}
+
+func (p *pendingIndex[T, R]) remove(id string) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	delete(p.jobs, id)
+}
+
+// values returns the indexed jobs, the index is rebuilt by the given function if it doesn't match the queue length
+func (p *pendingIndex[T, R]) values(queueLen int, load func() []iJob[T, R]) []iJob[T, R] {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if len(p.jobs) != queueLen {
+		p.jobs = make(map[string]iJob[T, R], queueLen)
+
+		for _, j := range load() {
+			p.jobs[j.ID()] = j
+		}
+	}
+
+	values := make([]iJob[T, R], 0, len(p.jobs))
+	for _, j := range p.jobs {
+		values = append(values, j)
+	}
+
+	return values
+}
+
+// clear drops the indexed jobs, e.g. once the queue is purged
+func (p *pendingIndex[T, R]) clear() {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.jobs = nil
+}
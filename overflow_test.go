@@ -0,0 +1,368 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundedQueue(t *testing.T) {
+	double := func(data int) (int, error) {
+		return data * 2, nil
+	}
+
+	t.Run("rejects new jobs by default", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(2))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		_, ok1 := q.Add(1)
+		_, ok2 := q.Add(2)
+		job, ok := q.Add(3)
+
+		assert.True(t, ok1)
+		assert.True(t, ok2)
+		assert.False(t, ok)
+		assert.NotNil(t, job)
+		assert.Equal(t, 2, q.NumPending())
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		assert.NoError(t, w.Resume())
+		q.WaitUntilFinished()
+
+		_, ok = q.Add(4)
+		assert.True(t, ok, "there should be room once the jobs are processed")
+	})
+
+	t.Run("counts delayed jobs", func(t *testing.T) {
+		q := NewWorker(double, WithMaxPending(1)).BindQueue()
+		defer q.Close()
+
+		_, ok := q.Add(1, WithDelay(time.Hour))
+		assert.True(t, ok)
+
+		job, ok := q.Add(2)
+		assert.False(t, ok)
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+	})
+
+	t.Run("AddWait waits for room", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(1))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		_, ok := q.Add(1)
+		assert.True(t, ok)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+
+		job, err := q.AddWait(ctx, 2)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Nil(t, job)
+
+		added := make(chan EnqueuedJob[int])
+		go func() {
+			job, err := q.AddWait(context.Background(), 3)
+			assert.NoError(t, err)
+			added <- job
+		}()
+
+		select {
+		case <-added:
+			t.Fatal("AddWait should wait while the queue is full")
+		case <-time.After(30 * time.Millisecond):
+		}
+
+		assert.NoError(t, w.Resume())
+
+		select {
+		case job := <-added:
+			result, err := job.Result()
+			assert.NoError(t, err)
+			assert.Equal(t, 6, result)
+		case <-time.After(time.Second):
+			t.Fatal("AddWait should add the job once there is room")
+		}
+	})
+
+	t.Run("block policy makes Add wait", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(1), WithOverflowPolicy(OverflowBlock))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		q.Add(1)
+
+		added := make(chan bool)
+		go func() {
+			_, ok := q.Add(2)
+			added <- ok
+		}()
+
+		select {
+		case <-added:
+			t.Fatal("Add should block while the queue is full")
+		case <-time.After(30 * time.Millisecond):
+		}
+
+		assert.NoError(t, w.Resume())
+
+		select {
+		case ok := <-added:
+			assert.True(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("Add should return once there is room")
+		}
+	})
+
+	t.Run("a dispatched parked job makes room", func(t *testing.T) {
+		release := make(chan struct{})
+		w := NewWorker(func(data int) (int, error) {
+			<-release
+			return data * 2, nil
+		}, WithConcurrency(2), WithMaxPending(1), WithOverflowPolicy(OverflowBlock), WithSerialKeys())
+		q := w.BindQueue()
+		defer q.Close()
+
+		q.Add(1, WithJobKey("a"))
+		parked, _ := q.Add(2, WithJobKey("a"))
+
+		// the second job is parked behind the first one of its key, so the queue is full
+		assert.Eventually(t, func() bool { return q.NumPending() == 1 && w.NumProcessing() == 1 }, time.Second, time.Millisecond)
+
+		added := make(chan error)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			_, err := q.AddWait(ctx, 3)
+			added <- err
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+
+		select {
+		case err := <-added:
+			assert.NoError(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("AddWait should return once the parked job is dispatched")
+		}
+
+		result, err := parked.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+	})
+
+	t.Run("purge makes room", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(1))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		q.Add(1)
+		q.Purge()
+
+		_, ok := q.Add(2)
+		assert.True(t, ok)
+	})
+
+	t.Run("drop oldest policy", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(2), WithOverflowPolicy(OverflowDropOldest))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		oldest, _ := q.Add(1)
+		second, _ := q.Add(2)
+		newest, ok := q.Add(3)
+		assert.True(t, ok)
+		assert.Equal(t, 2, q.NumPending())
+
+		_, err := oldest.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		assert.NoError(t, w.Resume())
+
+		result, err := second.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+
+		result, err = newest.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 6, result)
+	})
+
+	t.Run("drop lowest priority policy", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(2), WithOverflowPolicy(OverflowDropLowestPriority))
+		q := w.BindPriorityQueue()
+		w.Pause()
+		defer q.Close()
+
+		high, _ := q.Add(1, 1)
+		low, _ := q.Add(2, 5)
+
+		// a job with a lower priority than all the pending ones is rejected
+		rejected, ok := q.Add(3, 9)
+		assert.False(t, ok)
+		_, err := rejected.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		urgent, ok := q.Add(4, 0)
+		assert.True(t, ok)
+		assert.Equal(t, 2, q.NumPending())
+
+		_, err = low.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		assert.NoError(t, w.Resume())
+
+		result, err := urgent.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 8, result)
+
+		result, err = high.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result)
+	})
+
+	t.Run("drop policy finishes the dropped job outside of the admission lock", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(1), WithOverflowPolicy(OverflowDropOldest))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		locked := make(chan bool, 1)
+		impl := q.(*queue[int, int]).worker
+		w.OnClose(func(e Event) {
+			if !errors.Is(e.Err, ErrQueueFull) {
+				return
+			}
+
+			free := impl.admissionMx.TryLock()
+			if free {
+				impl.admissionMx.Unlock()
+			}
+
+			locked <- !free
+		})
+
+		q.Add(1)
+		_, ok := q.Add(2)
+		assert.True(t, ok)
+		assert.False(t, <-locked, "the hooks of the dropped job should not run under the admission lock")
+	})
+
+	t.Run("drop policy keeps the jobs of a queue those can't remove them", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(1), WithOverflowPolicy(OverflowDropOldest))
+		q := w.WithPersistentQueue(newMockPersistentQueue())
+		w.Pause()
+		defer q.Close()
+
+		oldest, ok := q.Add(1, WithJobId("job-1"))
+		assert.True(t, ok)
+
+		job, ok := q.Add(2, WithJobId("job-2"))
+		assert.False(t, ok)
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		assert.Equal(t, 1, q.NumPending())
+		assert.Equal(t, "Queued", oldest.Status(), "the job those can't be removed should not be dropped")
+	})
+
+	t.Run("drop policy with a persistent queue", func(t *testing.T) {
+		mq := &countingValuesQueue{mockRemovableQueue: mockRemovableQueue{newMockPersistentQueue()}}
+		w := NewWorker(double, WithMaxPending(2), WithOverflowPolicy(OverflowDropOldest))
+		q := w.WithPersistentQueue(mq)
+		w.Pause()
+		defer q.Close()
+
+		oldest, _ := q.Add(1, WithJobId("job-1"))
+		q.Add(2, WithJobId("job-2"))
+
+		for i := 3; i <= 5; i++ {
+			_, ok := q.Add(i, WithJobId(fmt.Sprintf("job-%d", i)))
+			assert.True(t, ok)
+		}
+
+		_, err := oldest.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+		assert.Equal(t, 2, q.NumPending())
+		assert.Zero(t, mq.valuesCalls.Load(), "the payloads should not be decoded to pick the victims")
+
+		assert.NoError(t, w.Resume())
+		q.WaitUntilFinished()
+	})
+
+	t.Run("AddAll rejects the overflowed items", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(2))
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		group := q.AddAll([]Item[int]{{Value: 1}, {Value: 2}, {Value: 3}})
+		assert.Equal(t, 2, q.NumPending())
+
+		assert.NoError(t, w.Resume())
+
+		results, err := group.Results()
+		assert.NoError(t, err)
+
+		var succeeded, rejected int
+		for result := range results {
+			if result.Err != nil {
+				assert.ErrorIs(t, result.Err, ErrQueueFull)
+				rejected++
+			} else {
+				succeeded++
+			}
+		}
+
+		assert.Equal(t, 2, succeeded)
+		assert.Equal(t, 1, rejected)
+	})
+
+	t.Run("persistent queue", func(t *testing.T) {
+		w := NewWorker(double, WithMaxPending(1))
+		q := w.WithPersistentQueue(newMockPersistentQueue())
+		w.Pause()
+		defer q.Close()
+
+		_, ok := q.Add(1, WithJobId("job-1"))
+		assert.True(t, ok)
+
+		job, ok := q.Add(2, WithJobId("job-2"))
+		assert.False(t, ok)
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err = q.AddWait(ctx, 3, WithJobId("job-3"))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// countingValuesQueue is a mockRemovableQueue those counts the calls of Values
+type countingValuesQueue struct {
+	mockRemovableQueue
+	valuesCalls atomic.Int32
+}
+
+func (q *countingValuesQueue) Values() []any {
+	q.valuesCalls.Add(1)
+	return q.mockRemovableQueue.Values()
+}
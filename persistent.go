@@ -1,5 +1,7 @@
 package varmq
 
+import "context"
+
 // PersistentQueue is an interface that extends Queue to support persistent job operations
 // where jobs can be recovered even after application restarts. All jobs must have unique IDs.
 type PersistentQueue[T, R any] interface {
@@ -25,48 +27,72 @@ func newPersistentQueue[T, R any](w *worker[T, R], pq IPersistentQueue) Persiste
 // It will panic if no job ID is provided
 // Returns an EnqueuedJob that can be used to track the job's status and result
 func (q *persistentQueue[T, R]) Add(data T, configs ...JobConfigFunc) (EnqueuedJob[R], bool) {
+	j, err := q.add(context.Background(), q.configs.Overflow == OverflowBlock, data, configs...)
+
+	return j, err == nil
+}
+
+// AddWait adds a job with the given data to the persistent queue, waiting until there is room for it if the queue is full
+// It requires a job ID to be provided in the job config for persistence
+func (q *persistentQueue[T, R]) AddWait(ctx context.Context, data T, configs ...JobConfigFunc) (EnqueuedJob[R], error) {
+	j, err := q.add(ctx, true, data, configs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (q *persistentQueue[T, R]) add(ctx context.Context, wait bool, data T, configs ...JobConfigFunc) (EnqueuedJob[R], error) {
 	jobConfig := withRequiredJobId(loadJobConfigs(q.configs, configs...))
 
 	j := newJob[T, R](data, jobConfig)
-	val, err := j.Json()
+	val, err := j.encode(q.configs.Codec)
 
 	if err != nil {
-		return nil, false
+		return nil, err
 	}
 
-	if ok := q.internalQueue.Enqueue(val); !ok {
-		j.close()
-		return nil, false
+	if err := q.addJob(ctx, j, wait, func() bool {
+		return q.internalQueue.Enqueue(val)
+	}); err != nil {
+		return j, err
 	}
 
 	j.SetInternalQueue(q.internalQueue)
 
 	q.postEnqueue(j)
 
-	return j, true
+	return j, nil
+}
+
+func (q *persistentQueue[T, R]) requireJobId() error {
+	return q.requireJobIdGenerator()
 }
 
 // AddAll adds multiple jobs to the persistent queue at once
 // Each item must have a unique ID for persistence
 // Returns an EnqueuedGroupJob that can be used to track all jobs' statuses and results
 // Will panic if any job is missing an ID
-func (q *persistentQueue[T, R]) AddAll(items []Item[T]) EnqueuedGroupJob[R] {
+func (q *persistentQueue[T, R]) AddAll(items []Item[T], configs ...JobConfigFunc) EnqueuedGroupJob[R] {
 	l := len(items)
 	groupJob := newGroupJob[T, R](l)
+	wait := q.configs.Overflow == OverflowBlock
 
 	for _, item := range items {
-		jConfigs := withRequiredJobId(loadJobConfigs(q.configs, WithJobId(item.ID)))
+		jConfigs := withRequiredJobId(loadItemJobConfigs(q.configs, item.ID, configs...))
 
 		j := groupJob.NewJob(item.Value, jConfigs)
-		val, err := j.Json()
+		val, err := j.encode(q.configs.Codec)
 
 		if err != nil {
 			j.close()
 			continue
 		}
 
-		if ok := q.internalQueue.Enqueue(val); !ok {
-			j.close()
+		if err := q.addJob(context.Background(), j, wait, func() bool {
+			return q.internalQueue.Enqueue(val)
+		}); err != nil {
 			continue
 		}
 
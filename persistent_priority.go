@@ -1,5 +1,7 @@
 package varmq
 
+import "context"
+
 type PersistentPriorityQueue[T, R any] interface {
 	PriorityQueue[T, R]
 }
@@ -16,41 +18,65 @@ func newPersistentPriorityQueue[T, R any](worker *worker[T, R], pq IPersistentPr
 }
 
 func (q *persistentPriorityQueue[T, R]) Add(data T, priority int, configs ...JobConfigFunc) (EnqueuedJob[R], bool) {
+	j, err := q.add(context.Background(), q.configs.Overflow == OverflowBlock, data, priority, configs...)
+
+	return j, err == nil
+}
+
+func (q *persistentPriorityQueue[T, R]) AddWait(ctx context.Context, data T, priority int, configs ...JobConfigFunc) (EnqueuedJob[R], error) {
+	j, err := q.add(ctx, true, data, priority, configs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (q *persistentPriorityQueue[T, R]) requireJobId() error {
+	return q.requireJobIdGenerator()
+}
+
+func (q *persistentPriorityQueue[T, R]) add(ctx context.Context, wait bool, data T, priority int, configs ...JobConfigFunc) (EnqueuedJob[R], error) {
 	jobConfig := withRequiredJobId(loadJobConfigs(q.configs, configs...))
 
 	j := newJob[T, R](data, jobConfig)
-	val, err := j.Json()
+	j.SetPriority(priority)
+	val, err := j.encode(q.configs.Codec)
 	if err != nil {
-		return nil, false
+		return nil, err
 	}
 	j.SetInternalQueue(q.internalQueue)
 
-	if ok := q.internalQueue.Enqueue(val, priority); !ok {
-		j.close()
-		return nil, false
+	if err := q.addJob(ctx, j, wait, func() bool {
+		return q.internalQueue.Enqueue(val, priority)
+	}); err != nil {
+		return j, err
 	}
 
 	q.postEnqueue(j)
 
-	return j, true
+	return j, nil
 }
 
-func (q *persistentPriorityQueue[T, R]) AddAll(items []Item[T]) EnqueuedGroupJob[R] {
+func (q *persistentPriorityQueue[T, R]) AddAll(items []Item[T], configs ...JobConfigFunc) EnqueuedGroupJob[R] {
 	groupJob := newGroupJob[T, R](len(items))
+	wait := q.configs.Overflow == OverflowBlock
 
 	for _, item := range items {
-		jConfigs := withRequiredJobId(loadJobConfigs(q.configs, WithJobId(item.ID)))
+		jConfigs := withRequiredJobId(loadItemJobConfigs(q.configs, item.ID, configs...))
 
 		j := groupJob.NewJob(item.Value, jConfigs)
-		val, err := j.Json()
+		j.SetPriority(item.Priority)
+		val, err := j.encode(q.configs.Codec)
 		if err != nil {
 			j.close()
 			continue
 		}
 		j.SetInternalQueue(q.internalQueue)
 
-		if ok := q.internalQueue.Enqueue(val, item.Priority); !ok {
-			j.close()
+		if err := q.addJob(context.Background(), j, wait, func() bool {
+			return q.internalQueue.Enqueue(val, item.Priority)
+		}); err != nil {
 			continue
 		}
 
@@ -0,0 +1,238 @@
+package varmq
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockPersistentQueue is an in-memory implementation of IPersistentQueue for testing
+type mockPersistentQueue struct {
+	items    [][]byte
+	unacked  map[string][]byte
+	acked    []string
+	enqueued [][]byte
+	nextAck  int
+	mx       sync.Mutex
+}
+
+func newMockPersistentQueue() *mockPersistentQueue {
+	return &mockPersistentQueue{
+		items:   make([][]byte, 0),
+		unacked: make(map[string][]byte),
+	}
+}
+
+func (q *mockPersistentQueue) Len() int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return len(q.items)
+}
+
+func (q *mockPersistentQueue) Enqueue(item any) bool {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	data, ok := item.([]byte)
+	if !ok {
+		return false
+	}
+
+	q.items = append(q.items, data)
+	q.enqueued = append(q.enqueued, data)
+	return true
+}
+
+func (q *mockPersistentQueue) Dequeue() (any, bool) {
+	v, ok, _ := q.DequeueWithAckId()
+	return v, ok
+}
+
+func (q *mockPersistentQueue) DequeueWithAckId() (any, bool, string) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, false, ""
+	}
+
+	item := q.items[0]
+	q.items = q.items[1:]
+	q.nextAck++
+	ackId := "ack-" + strconv.Itoa(q.nextAck)
+	q.unacked[ackId] = item
+
+	return item, true, ackId
+}
+
+func (q *mockPersistentQueue) Acknowledge(ackID string) bool {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	if _, ok := q.unacked[ackID]; !ok {
+		return false
+	}
+
+	delete(q.unacked, ackID)
+	q.acked = append(q.acked, ackID)
+	return true
+}
+
+func (q *mockPersistentQueue) Values() []any {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	values := make([]any, 0, len(q.items))
+	for _, item := range q.items {
+		values = append(values, item)
+	}
+	return values
+}
+
+func (q *mockPersistentQueue) Purge() {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	q.items = make([][]byte, 0)
+}
+
+func (q *mockPersistentQueue) Close() error {
+	q.Purge()
+	return nil
+}
+
+func (q *mockPersistentQueue) Unacked() int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return len(q.unacked)
+}
+
+func TestPersistentQueue(t *testing.T) {
+	t.Run("processing jobs", func(t *testing.T) {
+		mq := newMockPersistentQueue()
+		q := NewWorker(func(data int) (int, error) {
+			return data * 2, nil
+		}).WithPersistentQueue(mq)
+		defer q.Close()
+
+		job, ok := q.Add(21, WithJobId("job-1"))
+		assert.True(t, ok)
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 42, result)
+
+		q.WaitUntilFinished()
+		assert.Equal(t, 0, mq.Unacked(), "processed job should be acknowledged")
+	})
+
+	t.Run("retried job persists its attempts", func(t *testing.T) {
+		var calls atomic.Int32
+		mq := newMockPersistentQueue()
+
+		q := NewWorker(func(data int) (int, error) {
+			if calls.Add(1) == 1 {
+				return 0, errors.New("temporary error")
+			}
+
+			return data, nil
+		}, WithRetry(2, nil)).WithPersistentQueue(mq)
+		defer q.Close()
+
+		job, _ := q.Add(1, WithJobId("job-1"))
+		result, err := job.Result()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result)
+
+		q.WaitUntilFinished()
+
+		mq.mx.Lock()
+		defer mq.mx.Unlock()
+
+		assert.Len(t, mq.enqueued, 2, "failed job should be enqueued again")
+		assert.Contains(t, string(mq.enqueued[1]), `"attempts":1`, "re-enqueued job should carry the attempts")
+		assert.Empty(t, mq.unacked, "both deliveries should be acknowledged")
+	})
+
+	t.Run("restored job keeps its max attempts", func(t *testing.T) {
+		mq := newMockPersistentQueue()
+
+		// the job is enqueued by another worker, e.g. before a restart
+		j := newJob[int, int](1, loadJobConfigs(newConfig(), WithJobId("job-1"), WithRetry(3, nil)))
+		j.ChangeStatus(queued)
+		payload, err := j.encode(nil)
+		assert.NoError(t, err)
+		mq.Enqueue(payload)
+
+		var calls atomic.Int32
+		closed := make(chan Event, 1)
+
+		w := NewWorker(func(data int) (int, error) {
+			if calls.Add(1) < 3 {
+				return 0, errors.New("temporary error")
+			}
+
+			return data, nil
+		})
+		w.OnClose(func(e Event) { closed <- e })
+
+		q := w.WithPersistentQueue(mq)
+		defer q.Close()
+
+		select {
+		case e := <-closed:
+			assert.NoError(t, e.Err)
+			assert.Equal(t, int32(3), calls.Load(), "the job should be retried by its own policy")
+		case <-time.After(time.Second):
+			t.Fatal("restored job should be processed")
+		}
+	})
+	t.Run("not due job is handed back to a nackable distributed queue", func(t *testing.T) {
+		mq := NewMemoryQueue(time.Second)
+		processed := make(chan int, 1)
+
+		q := NewVoidWorker(func(data int) {
+			processed <- data
+		}).WithDistributedQueue(mq)
+		defer q.Close()
+
+		assert.True(t, q.Add(1, WithJobId("job"), WithDelay(100*time.Millisecond)))
+
+		// the delivery is nacked with a delay, so neither the queue nor the worker holds it meanwhile
+		assert.Eventually(t, func() bool {
+			return mq.Len() == 0 && mq.Inflight() == 0
+		}, 50*time.Millisecond, time.Millisecond)
+
+		select {
+		case data := <-processed:
+			assert.Equal(t, 1, data)
+		case <-time.After(time.Second):
+			t.Fatal("job should be processed once it's due")
+		}
+	})
+
+	t.Run("not due jobs of a queue those can't take them back are held up to a bound", func(t *testing.T) {
+		mq := newMockPersistentQueue()
+
+		q := NewWorker(func(data int) (int, error) {
+			return data, nil
+		}).WithPersistentQueue(mq)
+		defer q.Close()
+
+		for i := range delayLookahead + 1 {
+			_, ok := q.Add(i, WithJobId(strconv.Itoa(i)), WithDelay(time.Hour))
+			assert.True(t, ok)
+		}
+
+		assert.Eventually(t, func() bool {
+			return q.(*persistentQueue[int, int]).delayed.Len() == delayLookahead
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, 1, mq.Len(), "the queue should not be pulled further")
+		assert.Equal(t, delayLookahead+1, q.NumPending())
+	})
+}
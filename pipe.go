@@ -0,0 +1,282 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errStageStopped is the error of a pipeline those next stage is stopped before the job is added into it
+var errStageStopped = errors.New("next stage of the pipeline is stopped")
+
+// PipeStage is a stage of a pipeline, e.g. Queue, PersistentQueue or another Pipeline.
+// The jobs of the next stages are added without ids, so a persistent stage needs WithJobIdGenerator.
+type PipeStage[T, R any] interface {
+	Add(data T, configs ...JobConfigFunc) (EnqueuedJob[R], bool)
+}
+
+// waitingStage is a stage those can wait for room while it's full, e.g. Queue or PersistentQueue
+type waitingStage[T, R any] interface {
+	AddWait(ctx context.Context, data T, configs ...JobConfigFunc) (EnqueuedJob[R], error)
+}
+
+// stoppableStage is a stage processed by a worker, those stop context is cancelled once the worker is stopped
+type stoppableStage interface {
+	stopContext() context.Context
+}
+
+// Pipeline chains the queues of its stages, the result of a stage is added into the queue of the next one.
+type Pipeline[T, R any] struct {
+	add func(data T, configs ...JobConfigFunc) (EnqueuedJob[R], bool)
+}
+
+// Pipe chains the given stages, so the result of a job of the first stage is added into the next stage once it succeeds.
+// Each stage is processed by its own worker with its own concurrency, and the stages are followed by Then without
+// any goroutine waiting for their results. Pipes can be nested to chain more stages.
+//
+// The result is added into the next stage in background, waiting for room if the next stage is full,
+// so the worker of the first stage is never held by the next one. The job resolves with an error
+// if the next stage rejects the result or it's stopped before the result is added.
+//
+// Example:
+//
+//	pipeline := varmq.Pipe(varmq.Pipe(fetchQueue, parseQueue), storeQueue)
+//	job, ok := pipeline.Add("https://example.com")
+//	stored, err := job.Result()
+func Pipe[A, B, C any](first PipeStage[A, B], next PipeStage[B, C]) *Pipeline[A, C] {
+	return &Pipeline[A, C]{
+		add: func(data A, configs ...JobConfigFunc) (EnqueuedJob[C], bool) {
+			j, ok := first.Add(data, configs...)
+			p := newPipeJob[C](j)
+
+			if j == nil {
+				p.resolve(Result[C]{Err: errEnqueueFailed})
+				return p, false
+			}
+
+			j.Then(func(data B, err error) {
+				if err != nil {
+					p.resolve(Result[C]{JobId: p.id, Err: err})
+					return
+				}
+
+				go p.advance(func(ctx context.Context) (EnqueuedJob[C], error) {
+					return addToStage(ctx, next, data)
+				})
+			})
+
+			return p, ok
+		},
+	}
+}
+
+// addToStage adds the data into the stage, waiting for room until the context is done if the stage supports it.
+// Waiting is given up once the worker of the stage is stopped.
+func addToStage[T, R any](ctx context.Context, stage PipeStage[T, R], data T) (EnqueuedJob[R], error) {
+	if s, ok := stage.(stoppableStage); ok {
+		stopped := s.stopContext()
+		if stopped.Err() != nil {
+			return nil, errStageStopped
+		}
+
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		defer context.AfterFunc(stopped, func() { cancel(errStageStopped) })()
+	}
+
+	if s, ok := stage.(waitingStage[T, R]); ok {
+		j, err := s.AddWait(ctx, data)
+		if err != nil && ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+
+		return j, err
+	}
+
+	j, ok := stage.Add(data)
+	if ok {
+		return j, nil
+	}
+
+	if j == nil {
+		return nil, errEnqueueFailed
+	}
+
+	// the rejected job is resolved with the reason right away
+	_, err := j.Result()
+	return nil, selectError(err, errEnqueueFailed)
+}
+
+// Add adds the data into the first stage, the configs are applied to the job of the first stage only.
+// The returned job resolves with the result of the last stage, or the error of the first failed stage.
+// It returns false if the job couldn't be added into the first stage.
+func (p *Pipeline[T, R]) Add(data T, configs ...JobConfigFunc) (EnqueuedJob[R], bool) {
+	return p.add(data, configs...)
+}
+
+// stageJob is the job of the current stage of a pipeline
+type stageJob interface {
+	Job
+	Cancel() error
+}
+
+// pipeJob is the job of a pipeline, following the job of its current stage
+type pipeJob[R any] struct {
+	*future[R]
+	id        string
+	current   stageJob
+	cancelled bool
+	// stopAdding gives up adding the job of the next stage, it's set while the job is being added
+	stopAdding context.CancelCauseFunc
+	mx         sync.Mutex
+}
+
+func newPipeJob[R any](first stageJob) *pipeJob[R] {
+	p := &pipeJob[R]{
+		future:  newFuture[R](nil),
+		current: first,
+	}
+
+	if first != nil {
+		p.id = first.ID()
+	}
+
+	return p
+}
+
+// advance adds the job of the next stage, unless the pipeline is cancelled in the meantime.
+// Adding is given up once the pipeline is cancelled, and the pipeline resolves with the error of adding if any.
+func (p *pipeJob[R]) advance(add func(ctx context.Context) (EnqueuedJob[R], error)) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	p.mx.Lock()
+	if p.cancelled {
+		p.mx.Unlock()
+		p.resolve(Result[R]{JobId: p.id, Err: ErrJobCancelled})
+		return
+	}
+
+	p.stopAdding = cancel
+	p.mx.Unlock()
+
+	j, err := add(ctx)
+
+	p.mx.Lock()
+	p.stopAdding = nil
+	if j != nil {
+		p.current = j
+	}
+	cancelled := p.cancelled
+	p.mx.Unlock()
+
+	if err != nil || j == nil {
+		p.resolve(Result[R]{JobId: p.id, Err: selectError(err, errEnqueueFailed)})
+		return
+	}
+
+	// cancelled while the job was being added
+	if cancelled {
+		j.Cancel()
+	}
+
+	j.Then(func(data R, err error) {
+		p.resolve(Result[R]{JobId: p.id, Data: data, Err: err})
+	})
+}
+
+// resolve sets the outcome of the pipeline, the result of a cancelled pipeline is discarded like the one of a cancelled job
+func (p *pipeJob[R]) resolve(r Result[R]) {
+	if p.isCancelled() && r.Err == nil {
+		r = Result[R]{JobId: p.id, Err: ErrJobCancelled}
+	}
+
+	p.future.resolve(r)
+}
+
+func (p *pipeJob[R]) isCancelled() bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.cancelled
+}
+
+// ID returns the id of the job of the first stage.
+func (p *pipeJob[R]) ID() string {
+	return p.id
+}
+
+// IsClosed returns true once the pipeline is resolved.
+func (p *pipeJob[R]) IsClosed() bool {
+	return p.isResolved()
+}
+
+// Status returns the status of the job of the current stage, or Closed once the pipeline is resolved.
+func (p *pipeJob[R]) Status() string {
+	if p.isResolved() {
+		return statusName(closed)
+	}
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.current == nil {
+		return statusName(closed)
+	}
+
+	return p.current.Status()
+}
+
+// Json returns the JSON representation of the job of the current stage.
+func (p *pipeJob[R]) Json() ([]byte, error) {
+	p.mx.Lock()
+	current := p.current
+	p.mx.Unlock()
+
+	if current == nil {
+		return nil, errors.New("pipeline has no job")
+	}
+
+	return current.Json()
+}
+
+func (p *pipeJob[R]) close() error {
+	return errors.New("pipeline job is closed once it's resolved")
+}
+
+// Drain discards the result of the pipeline, there is nothing to drain since its result isn't sent over a channel.
+func (p *pipeJob[R]) Drain() error {
+	return nil
+}
+
+// Cancel cancels the job of the current stage, and stops the pipeline from adding the next ones.
+func (p *pipeJob[R]) Cancel() error {
+	if p.isResolved() {
+		return errors.New("job is already finished")
+	}
+
+	p.mx.Lock()
+
+	if p.cancelled {
+		p.mx.Unlock()
+		return errors.New("job is already cancelled")
+	}
+
+	p.cancelled = true
+	current := p.current
+	stopAdding := p.stopAdding
+	p.mx.Unlock()
+
+	if stopAdding != nil {
+		stopAdding(ErrJobCancelled)
+	}
+
+	// the current stage might be finished already, then the next one is not added
+	if current != nil {
+		current.Cancel()
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package varmq
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipe(t *testing.T) {
+	length := func(s string) (int, error) {
+		return len(s), nil
+	}
+
+	double := func(n int) (int, error) {
+		return n * 2, nil
+	}
+
+	format := func(n int) (string, error) {
+		return strconv.Itoa(n), nil
+	}
+
+	t.Run("adds the result of a stage into the next one", func(t *testing.T) {
+		first := NewWorker(length).BindQueue()
+		defer first.WaitAndClose()
+		second := NewWorker(double, WithConcurrency(2)).BindQueue()
+		defer second.WaitAndClose()
+
+		job, ok := Pipe(first, second).Add("abc", WithJobId("job-1"))
+		assert.True(t, ok)
+		assert.Equal(t, "job-1", job.ID())
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 6, result)
+		assert.True(t, job.IsClosed())
+	})
+
+	t.Run("chains nested pipes", func(t *testing.T) {
+		first := NewWorker(length).BindQueue()
+		defer first.WaitAndClose()
+		second := NewWorker(double).BindQueue()
+		defer second.WaitAndClose()
+		third := NewWorker(format).BindQueue()
+		defer third.WaitAndClose()
+
+		pipeline := Pipe(Pipe(first, second), third)
+
+		jobs := make([]EnqueuedJob[string], 0, 10)
+		for i := range 10 {
+			job, ok := pipeline.Add(string(make([]byte, i)))
+			assert.True(t, ok)
+			jobs = append(jobs, job)
+		}
+
+		for i, job := range jobs {
+			result, err := job.Result()
+			assert.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(i*2), result)
+		}
+	})
+
+	t.Run("resolves with the error of the first failed stage", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		var called atomic.Bool
+
+		first := NewWorker(func(s string) (int, error) {
+			return 0, errFailed
+		}).BindQueue()
+		defer first.WaitAndClose()
+		second := NewWorker(func(n int) (int, error) {
+			called.Store(true)
+			return n, nil
+		}).BindQueue()
+		defer second.WaitAndClose()
+
+		job, _ := Pipe(first, second).Add("abc")
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, errFailed)
+		assert.False(t, called.Load(), "the next stage should not run")
+	})
+
+	t.Run("resolves when the next stage is purged", func(t *testing.T) {
+		first := NewWorker(length).BindQueue()
+		defer first.WaitAndClose()
+		w := NewWorker(double)
+		second := w.BindQueue()
+		w.Pause()
+
+		job, _ := Pipe(first, second).Add("abc")
+
+		assert.Eventually(t, func() bool {
+			return second.NumPending() == 1
+		}, time.Second, time.Millisecond)
+
+		assert.NoError(t, second.Close())
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobPurged)
+	})
+
+	t.Run("cancels the current stage", func(t *testing.T) {
+		first := NewWorker(length).BindQueue()
+		defer first.WaitAndClose()
+		w := NewWorker(double)
+		second := w.BindQueue()
+		w.Pause()
+		defer second.Close()
+
+		job, _ := Pipe(first, second).Add("abc")
+
+		assert.Eventually(t, func() bool {
+			return second.NumPending() == 1
+		}, time.Second, time.Millisecond)
+
+		assert.NoError(t, job.Cancel())
+		assert.Error(t, job.Cancel())
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+		assert.Zero(t, second.NumPending())
+	})
+
+	t.Run("doesn't hold the first stage while the next one is full", func(t *testing.T) {
+		first := NewWorker(length).BindQueue()
+		defer first.WaitAndClose()
+		w := NewWorker(double, WithMaxPending(1), WithOverflowPolicy(OverflowBlock))
+		second := w.BindQueue()
+		w.Pause()
+		defer second.Close()
+
+		pipeline := Pipe(first, second)
+		queued, _ := pipeline.Add("a")
+		waiting, _ := pipeline.Add("bc")
+
+		assert.Eventually(t, func() bool {
+			return second.NumPending() == 1
+		}, time.Second, time.Millisecond)
+
+		// the first stage is free to process the other jobs, though the result of the second one waits for room
+		job, _ := first.Add("def")
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result)
+
+		assert.NoError(t, waiting.Cancel())
+		_, err = waiting.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+
+		assert.NoError(t, w.Resume())
+		result, err = queued.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result)
+	})
+
+	t.Run("resolves when the next stage is stopped", func(t *testing.T) {
+		first := NewWorker(length).BindQueue()
+		defer first.WaitAndClose()
+		w := NewWorker(double, WithMaxPending(1), WithOverflowPolicy(OverflowBlock))
+		second := w.BindQueue()
+		w.Pause()
+
+		pipeline := Pipe(first, second)
+		pipeline.Add("a")
+		waiting, _ := pipeline.Add("bc")
+
+		assert.Eventually(t, func() bool {
+			return second.NumPending() == 1
+		}, time.Second, time.Millisecond)
+
+		w.Stop()
+
+		_, err := waiting.Result()
+		assert.ErrorIs(t, err, errStageStopped)
+
+		job, _ := pipeline.Add("def")
+		_, err = job.Result()
+		assert.ErrorIs(t, err, errStageStopped)
+	})
+
+	t.Run("resolves when the next stage rejects the result", func(t *testing.T) {
+		first := NewWorker(length).BindQueue()
+		defer first.WaitAndClose()
+		w := NewWorker(double, WithMaxPending(1))
+		second := w.BindQueue()
+		w.Pause()
+		defer second.Close()
+
+		pipeline := Pipe(first, second)
+		pipeline.Add("a")
+
+		assert.Eventually(t, func() bool {
+			return second.NumPending() == 1
+		}, time.Second, time.Millisecond)
+
+		job, _ := Pipe(first, PipeStage[int, int](rejectingStage[int, int]{second})).Add("bc")
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+	})
+
+	t.Run("reports the rejected first stage", func(t *testing.T) {
+		w := NewWorker(length, WithMaxPending(1))
+		first := w.BindQueue()
+		w.Pause()
+		defer first.Close()
+		second := NewWorker(double).BindQueue()
+		defer second.WaitAndClose()
+
+		pipeline := Pipe(first, second)
+		_, ok := pipeline.Add("a")
+		assert.True(t, ok)
+
+		job, ok := pipeline.Add("b")
+		assert.False(t, ok)
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrQueueFull)
+	})
+}
+
+// rejectingStage hides AddWait of the queue, so the results are added by Add those rejects them while the queue is full
+type rejectingStage[T, R any] struct {
+	q Queue[T, R]
+}
+
+func (s rejectingStage[T, R]) Add(data T, configs ...JobConfigFunc) (EnqueuedJob[R], bool) {
+	return s.q.Add(data, configs...)
+}
@@ -1,5 +1,7 @@
 package varmq
 
+import "context"
+
 type priorityQueue[T, R any] struct {
 	*externalQueue[T, R]
 	internalQueue IPriorityQueue
@@ -8,11 +10,17 @@ type priorityQueue[T, R any] struct {
 type PriorityQueue[T, R any] interface {
 	IExternalQueue[T, R]
 	// Add adds a new Job with the given priority to the queue and returns a channel to receive the result.
+	// If the job can't be added, it returns false and Result of the job returns the reason, e.g. ErrQueueFull.
 	// Time complexity: O(log n)
 	Add(data T, priority int, configs ...JobConfigFunc) (EnqueuedJob[R], bool)
+	// AddWait adds a new Job with the given priority to the queue, waiting until there is room for it if the queue is full.
+	// It returns the context error if the context is done before the job is added.
+	// Time complexity: O(log n)
+	AddWait(ctx context.Context, data T, priority int, configs ...JobConfigFunc) (EnqueuedJob[R], error)
 	// AddAll adds multiple Jobs with the given priority to the queue and returns a channel to receive all responses.
+	// The given configs are applied to every job, except the job id which is taken from the item.
 	// Time complexity: O(n log n) where n is the number of Jobs added
-	AddAll(data []Item[T]) EnqueuedGroupJob[R]
+	AddAll(data []Item[T], configs ...JobConfigFunc) EnqueuedGroupJob[R]
 }
 
 // NewPriorityQueue creates a new priorityQueue with the specified concurrency and worker function.
@@ -26,27 +34,48 @@ func newPriorityQueue[T, R any](worker *worker[T, R], pq IPriorityQueue) *priori
 }
 
 func (q *priorityQueue[T, R]) Add(data T, priority int, configs ...JobConfigFunc) (EnqueuedJob[R], bool) {
+	j, err := q.add(context.Background(), q.configs.Overflow == OverflowBlock, data, priority, configs...)
+
+	return j, err == nil
+}
+
+func (q *priorityQueue[T, R]) AddWait(ctx context.Context, data T, priority int, configs ...JobConfigFunc) (EnqueuedJob[R], error) {
+	j, err := q.add(ctx, true, data, priority, configs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (q *priorityQueue[T, R]) add(ctx context.Context, wait bool, data T, priority int, configs ...JobConfigFunc) (EnqueuedJob[R], error) {
 	j := newJob[T, R](data, loadJobConfigs(q.configs, configs...))
+	j.SetPriority(priority)
 
-	if ok := q.internalQueue.Enqueue(j, priority); !ok {
-		j.close()
-		return nil, false
+	if err := q.addJob(ctx, j, wait, func() bool {
+		return q.delayJob(j) || q.internalQueue.Enqueue(j, priority)
+	}); err != nil {
+		return j, err
 	}
 
 	q.postEnqueue(j)
 
-	return j, true
+	return j, nil
 }
 
-func (q *priorityQueue[T, R]) AddAll(items []Item[T]) EnqueuedGroupJob[R] {
+func (q *priorityQueue[T, R]) AddAll(items []Item[T], configs ...JobConfigFunc) EnqueuedGroupJob[R] {
 	l := len(items)
 	groupJob := newGroupJob[T, R](l)
 
+	wait := q.configs.Overflow == OverflowBlock
+
 	for _, item := range items {
-		j := groupJob.NewJob(item.Value, loadJobConfigs(q.configs, WithJobId(item.ID)))
+		j := groupJob.NewJob(item.Value, loadItemJobConfigs(q.configs, item.ID, configs...))
+		j.SetPriority(item.Priority)
 
-		if ok := q.internalQueue.Enqueue(j, item.Priority); !ok {
-			j.close()
+		if err := q.addJob(context.Background(), j, wait, func() bool {
+			return q.delayJob(j) || q.internalQueue.Enqueue(j, item.Priority)
+		}); err != nil {
 			continue
 		}
 
@@ -0,0 +1,94 @@
+package varmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goptics/varmq/utils"
+)
+
+// QuarantinePolicy decides what happens to the delivery of a quarantined payload, see WithQuarantine.
+type QuarantinePolicy uint8
+
+const (
+	// QuarantineAck acknowledges the delivery once the payload is quarantined.
+	QuarantineAck QuarantinePolicy = iota
+	// QuarantineNack negatively acknowledges the delivery without requeueing it once the payload is quarantined,
+	// so the queue discards or dead letters it, see INackable. It's acknowledged if the queue is not nackable.
+	QuarantineNack
+)
+
+// PoisonMessage is a payload dequeued from a persistent or distributed queue those couldn't be parsed to a job.
+type PoisonMessage struct {
+	// Payload is the raw payload as it's dequeued, a value those is not a []byte is formatted with fmt.
+	Payload []byte
+	// Err is the error the payload failed to be parsed with.
+	Err error
+	// AckId is the acknowledgement id of the delivery, empty if the queue is not acknowledgeable.
+	AckId string
+	// ReceivedAt is the time when the payload was dequeued.
+	ReceivedAt time.Time
+}
+
+// WithQuarantine hands the payloads those couldn't be parsed to jobs to the given sink, instead of the dead letter queue.
+// Once the sink succeeds, the delivery is settled by the given policy. If it fails or panics, the delivery is left unacknowledged,
+// so the payload is not lost and the queue can redeliver it, e.g. once its visibility timeout is over.
+func WithQuarantine(sink func(msg PoisonMessage) error, policy QuarantinePolicy) ConfigFunc {
+	return func(c *configs) {
+		c.Quarantine = sink
+		c.QuarantinePolicy = policy
+	}
+}
+
+// quarantine handles the payload those couldn't be parsed to a job, and settles its delivery
+func (w *worker[T, R]) quarantine(payload []byte, err error, ackId string) {
+	w.metrics.unparsable.Add(1)
+
+	sink := w.configs.Quarantine
+
+	if sink == nil {
+		// acknowledge the unparsable payload only if it's safely moved to the dead letter queue
+		if w.movePayloadToDeadLetterQueue(payload, err) {
+			w.acknowledge(ackId)
+		}
+
+		return
+	}
+
+	msg := PoisonMessage{
+		Payload:    payload,
+		Err:        err,
+		AckId:      ackId,
+		ReceivedAt: time.Now(),
+	}
+
+	var sinkErr error
+
+	// a panicking sink fails like the one returning an error, so the delivery is left unacknowledged
+	if err := utils.WithSafe("quarantine sink", func() { sinkErr = sink(msg) }); err != nil || sinkErr != nil {
+		return
+	}
+
+	if q, ok := w.Queue.(INackable); ok && ackId != "" && w.configs.QuarantinePolicy == QuarantineNack {
+		q.Nack(ackId, false, 0)
+		return
+	}
+
+	w.acknowledge(ackId)
+}
+
+// rawPayload returns the raw payload of a dequeued value those is not a []byte, to be quarantined
+func rawPayload(v any) []byte {
+	if s, ok := v.(string); ok {
+		return []byte(s)
+	}
+
+	return fmt.Appendf(nil, "%v", v)
+}
+
+// acknowledge acknowledges the delivery if the queue is acknowledgeable
+func (w *worker[T, R]) acknowledge(ackId string) {
+	if q, ok := w.Queue.(IAcknowledgeable); ok && ackId != "" {
+		q.Acknowledge(ackId)
+	}
+}
@@ -0,0 +1,150 @@
+package varmq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuarantine(t *testing.T) {
+	t.Run("unparsable payload is quarantined and acknowledged", func(t *testing.T) {
+		mq := newMockNackableQueue()
+		mq.Enqueue([]byte("not a job"))
+
+		quarantined := make(chan PoisonMessage, 1)
+		w := NewVoidWorker(func(_ int) {}, WithQuarantine(func(msg PoisonMessage) error {
+			quarantined <- msg
+			return nil
+		}, QuarantineAck))
+		q := w.WithPersistentQueue(mq)
+		defer q.Close()
+
+		msg := <-quarantined
+		assert.Equal(t, []byte("not a job"), msg.Payload)
+		assert.Error(t, msg.Err)
+		assert.Equal(t, "ack-1", msg.AckId)
+		assert.False(t, msg.ReceivedAt.IsZero())
+
+		assert.Eventually(t, func() bool {
+			return mq.Unacked() == 0
+		}, time.Second, time.Millisecond)
+		assert.Empty(t, mq.Nacks())
+		assert.Equal(t, uint64(1), w.Metrics().Unparsable)
+	})
+
+	t.Run("quarantined payload is nacked by the policy", func(t *testing.T) {
+		mq := newMockNackableQueue()
+		mq.Enqueue([]byte("not a job"))
+
+		q := NewVoidWorker(func(_ int) {}, WithQuarantine(func(msg PoisonMessage) error {
+			return nil
+		}, QuarantineNack)).WithPersistentQueue(mq)
+		defer q.Close()
+
+		assert.Eventually(t, func() bool {
+			return len(mq.Nacks()) == 1
+		}, time.Second, time.Millisecond)
+
+		assert.Equal(t, []nack{{ackId: "ack-1", requeue: false}}, mq.Nacks())
+		assert.Equal(t, 0, mq.Len())
+		assert.Empty(t, mq.acked)
+	})
+
+	t.Run("payload is left unacknowledged if the sink fails", func(t *testing.T) {
+		mq := newMockNackableQueue()
+		mq.Enqueue([]byte("not a job"))
+
+		w := NewVoidWorker(func(_ int) {}, WithQuarantine(func(msg PoisonMessage) error {
+			return errors.New("sink is down")
+		}, QuarantineAck))
+		q := w.WithPersistentQueue(mq)
+		defer q.Close()
+
+		assert.Eventually(t, func() bool {
+			return w.Metrics().Unparsable == 1
+		}, time.Second, time.Millisecond)
+
+		assert.Equal(t, 1, mq.Unacked())
+		assert.Empty(t, mq.Nacks())
+	})
+
+	t.Run("payload is left unacknowledged if the sink panics", func(t *testing.T) {
+		mq := newMockNackableQueue()
+		mq.Enqueue([]byte("not a job"))
+
+		w := NewVoidWorker(func(_ int) {}, WithQuarantine(func(msg PoisonMessage) error {
+			panic("sink is broken")
+		}, QuarantineAck))
+		q := w.WithPersistentQueue(mq)
+		defer q.Close()
+
+		assert.Eventually(t, func() bool {
+			return w.Metrics().Unparsable == 1
+		}, time.Second, time.Millisecond)
+
+		q.WaitUntilFinished()
+		assert.Equal(t, 1, mq.Unacked())
+		assert.Empty(t, mq.Nacks())
+	})
+
+	t.Run("unparsable payload is counted without a sink", func(t *testing.T) {
+		mq := newMockPersistentQueue()
+		mq.Enqueue([]byte("not a job"))
+
+		w := NewVoidWorker(func(_ int) {})
+		q := w.WithPersistentQueue(mq)
+		defer q.Close()
+
+		assert.Eventually(t, func() bool {
+			return w.Metrics().Unparsable == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, 1, mq.Unacked(), "payload should not be acknowledged without being moved")
+	})
+	t.Run("value of an unsupported type is quarantined", func(t *testing.T) {
+		mq := &stringValueQueue{mockPersistentQueue: newMockPersistentQueue()}
+		mq.Enqueue([]byte("not bytes"))
+
+		quarantined := make(chan PoisonMessage, 1)
+		w := NewVoidWorker(func(_ int) {}, WithQuarantine(func(msg PoisonMessage) error {
+			quarantined <- msg
+			return nil
+		}, QuarantineAck))
+		q := w.WithPersistentQueue(mq)
+		defer q.Close()
+
+		msg := <-quarantined
+		assert.Equal(t, []byte("not bytes"), msg.Payload)
+		assert.ErrorIs(t, msg.Err, errUnsupportedValue)
+
+		finished := make(chan struct{})
+		go func() {
+			q.WaitUntilFinished()
+			close(finished)
+		}()
+
+		select {
+		case <-finished:
+		case <-time.After(time.Second):
+			t.Fatal("WaitUntilFinished should return")
+		}
+
+		assert.Equal(t, 0, mq.Unacked())
+		assert.Equal(t, uint64(1), w.Metrics().Unparsable)
+	})
+}
+
+// stringValueQueue is a mockPersistentQueue those hands back the payloads as strings
+type stringValueQueue struct {
+	*mockPersistentQueue
+}
+
+func (q *stringValueQueue) DequeueWithAckId() (any, bool, string) {
+	v, ok, ackId := q.mockPersistentQueue.DequeueWithAckId()
+	if !ok {
+		return nil, false, ""
+	}
+
+	return string(v.([]byte)), true, ackId
+}
@@ -1,5 +1,7 @@
 package varmq
 
+import "context"
+
 // queue is the base implementation of the Queue interface
 // It contains an externalQueue for worker management and an internalQueue for job storage
 type queue[T, R any] struct {
@@ -10,11 +12,17 @@ type queue[T, R any] struct {
 type Queue[T, R any] interface {
 	IExternalQueue[T, R]
 	// Add adds a new Job to the queue and returns a EnqueuedJob to handle the job.
+	// If the job can't be added, it returns false and Result of the job returns the reason, e.g. ErrQueueFull.
 	// Time complexity: O(1)
 	Add(data T, configs ...JobConfigFunc) (EnqueuedJob[R], bool)
+	// AddWait adds a new Job to the queue, waiting until there is room for it if the queue is full.
+	// It returns the context error if the context is done before the job is added.
+	// Time complexity: O(1)
+	AddWait(ctx context.Context, data T, configs ...JobConfigFunc) (EnqueuedJob[R], error)
 	// AddAll adds multiple Jobs to the queue and returns a EnqueuedGroupJob to handle the job.
+	// The given configs are applied to every job, except the job id which is taken from the item.
 	// Time complexity: O(n) where n is the number of Jobs added
-	AddAll(data []Item[T]) EnqueuedGroupJob[R]
+	AddAll(data []Item[T], configs ...JobConfigFunc) EnqueuedGroupJob[R]
 }
 
 // Item represents a data item to be processed by a worker
@@ -37,26 +45,46 @@ func newQueue[T, R any](worker *worker[T, R], q IQueue) *queue[T, R] {
 }
 
 func (q *queue[T, R]) Add(data T, configs ...JobConfigFunc) (EnqueuedJob[R], bool) {
+	j, err := q.add(context.Background(), q.configs.Overflow == OverflowBlock, data, configs...)
+
+	return j, err == nil
+}
+
+func (q *queue[T, R]) AddWait(ctx context.Context, data T, configs ...JobConfigFunc) (EnqueuedJob[R], error) {
+	j, err := q.add(ctx, true, data, configs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (q *queue[T, R]) add(ctx context.Context, wait bool, data T, configs ...JobConfigFunc) (EnqueuedJob[R], error) {
 	j := newJob[T, R](data, loadJobConfigs(q.configs, configs...))
 
-	if ok := q.internalQueue.Enqueue(j); !ok {
-		j.close()
-		return nil, false
+	if err := q.addJob(ctx, j, wait, func() bool {
+		return q.delayJob(j) || q.internalQueue.Enqueue(j)
+	}); err != nil {
+		return j, err
 	}
 
 	q.postEnqueue(j)
 
-	return j, true
+	return j, nil
 }
 
-func (q *queue[T, R]) AddAll(items []Item[T]) EnqueuedGroupJob[R] {
+func (q *queue[T, R]) AddAll(items []Item[T], configs ...JobConfigFunc) EnqueuedGroupJob[R] {
 	l := len(items)
 	groupJob := newGroupJob[T, R](l)
 
+	wait := q.configs.Overflow == OverflowBlock
+
 	for _, item := range items {
-		j := groupJob.NewJob(item.Value, loadJobConfigs(q.configs, WithJobId(item.ID)))
-		if ok := q.internalQueue.Enqueue(j); !ok {
-			j.close()
+		j := groupJob.NewJob(item.Value, loadItemJobConfigs(q.configs, item.ID, configs...))
+
+		if err := q.addJob(context.Background(), j, wait, func() bool {
+			return q.delayJob(j) || q.internalQueue.Enqueue(j)
+		}); err != nil {
 			continue
 		}
 
@@ -1,8 +1,11 @@
 package varmq
 
 import (
+	"context"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -109,3 +112,239 @@ func TestQueue(t *testing.T) {
 		assert.Equal(t, 0, groupJob.Len(), "Group job should have no pending jobs")
 	})
 }
+
+// mockRemovableQueue is a persistent queue those supports removing pending items
+type mockRemovableQueue struct {
+	*mockPersistentQueue
+}
+
+func (q mockRemovableQueue) Remove(match func(item any) bool) bool {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	for i, item := range q.items {
+		if match(item) {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCancelJob(t *testing.T) {
+	// blockedQueue returns a queue with a single worker those is busy until release is closed
+	blockedQueue := func(t *testing.T) (*queue[int, int], chan struct{}) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		q := NewWorker(func(data int) (int, error) {
+			if data == 0 {
+				close(started)
+				<-release
+			}
+
+			return data, nil
+		}, 1, WithCache(new(sync.Map))).BindQueue()
+
+		q.Add(0)
+		<-started
+
+		return q.(*queue[int, int]), release
+	}
+
+	t.Run("pending job is removed from the queue", func(t *testing.T) {
+		q, release := blockedQueue(t)
+		defer q.Close()
+
+		job, _ := q.Add(1)
+		assert.Equal(t, 1, q.NumPending())
+
+		assert.NoError(t, job.Cancel())
+		assert.Equal(t, 0, q.NumPending(), "cancelled job should be removed from the queue")
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+		assert.Error(t, job.Cancel(), "cancelled job should not be cancelled again")
+
+		close(release)
+		q.WaitUntilFinished()
+	})
+
+	t.Run("pending job in priority queue", func(t *testing.T) {
+		release := make(chan struct{})
+		q := NewWorker(func(data int) (int, error) {
+			<-release
+			return data, nil
+		}, 1).BindPriorityQueue()
+		defer q.Close()
+
+		q.Add(0, 0)
+		job, _ := q.Add(1, 1)
+
+		assert.Eventually(t, func() bool { return q.NumPending() == 1 }, time.Second, time.Millisecond)
+		assert.NoError(t, job.Cancel())
+		assert.Equal(t, 0, q.NumPending())
+
+		close(release)
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+	})
+
+	t.Run("delayed job", func(t *testing.T) {
+		q := NewWorker(func(data int) (int, error) {
+			return data, nil
+		}).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1, WithDelay(time.Hour))
+		assert.NoError(t, job.Cancel())
+		assert.Equal(t, 0, q.NumPending())
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+	})
+
+	t.Run("running context-aware job", func(t *testing.T) {
+		started := make(chan struct{})
+		cause := make(chan error, 1)
+		var wf WorkerCtxFunc[int, int] = func(ctx context.Context, data int) (int, error) {
+			close(started)
+			<-ctx.Done()
+			cause <- context.Cause(ctx)
+			return 0, ctx.Err()
+		}
+
+		q := NewWorkerCtx(wf, WithRetry(3, nil)).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1)
+		<-started
+
+		assert.NoError(t, job.Cancel())
+		assert.ErrorIs(t, <-cause, ErrJobCancelled)
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+
+		q.WaitUntilFinished()
+		assert.Equal(t, 0, q.Worker().NumProcessing(), "cancelled job should not be retried")
+	})
+
+	t.Run("result of running job is discarded", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		q := NewWorker(func(data int) (int, error) {
+			close(started)
+			<-release
+			return data, nil
+		}).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1)
+		<-started
+
+		assert.NoError(t, job.Cancel())
+		close(release)
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled, "function without context should not override the cancellation")
+	})
+
+	t.Run("CancelJob by id", func(t *testing.T) {
+		q, release := blockedQueue(t)
+		defer q.Close()
+
+		job, _ := q.Add(1, WithJobId("job-1"))
+
+		assert.NoError(t, q.CancelJob("job-1"))
+		assert.Error(t, q.CancelJob("missing"))
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+
+		close(release)
+	})
+
+	t.Run("group", func(t *testing.T) {
+		q, release := blockedQueue(t)
+		defer q.Close()
+
+		group := q.AddAll([]Item[int]{{ID: "a", Value: 1}, {ID: "b", Value: 2}, {ID: "c", Value: 3}})
+
+		assert.NoError(t, q.CancelJob("a"), "a member should be cancelled alone")
+		assert.Equal(t, 2, q.NumPending())
+
+		assert.NoError(t, group.Cancel())
+		assert.Equal(t, 0, q.NumPending())
+		assert.Error(t, group.Cancel(), "finished group should not be cancelled")
+
+		results, _ := group.Results()
+		for result := range results {
+			assert.ErrorIs(t, result.Err, ErrJobCancelled)
+		}
+
+		group.Wait()
+		close(release)
+	})
+
+	t.Run("persistent queue", func(t *testing.T) {
+		release := make(chan struct{})
+		processed := make(chan int, 3)
+		mq := mockRemovableQueue{newMockPersistentQueue()}
+
+		q := NewWorker(func(data int) (int, error) {
+			<-release
+			processed <- data
+			return data, nil
+		}, 1, WithCache(new(sync.Map))).WithPersistentQueue(mq)
+		defer q.Close()
+
+		q.Add(1, WithJobId("job-1"))
+		job, _ := q.Add(2, WithJobId("job-2"))
+
+		assert.Eventually(t, func() bool { return q.NumPending() == 1 }, time.Second, time.Millisecond)
+		assert.NoError(t, job.Cancel())
+		assert.Equal(t, 0, mq.Len(), "cancelled job should be removed from the persistent queue")
+
+		close(release)
+		assert.Equal(t, 1, <-processed)
+		q.WaitUntilFinished()
+
+		assert.Len(t, processed, 0)
+		assert.Equal(t, 0, mq.Unacked())
+	})
+
+	t.Run("persistent queue without remove support", func(t *testing.T) {
+		release := make(chan struct{})
+		processed := make(chan int, 3)
+		mq := newMockPersistentQueue()
+
+		q := NewWorker(func(data int) (int, error) {
+			<-release
+			processed <- data
+			return data, nil
+		}, 1, WithCache(new(sync.Map))).WithPersistentQueue(mq)
+		defer q.Close()
+
+		q.Add(1, WithJobId("job-1"))
+		job, _ := q.Add(2, WithJobId("job-2"))
+
+		assert.Eventually(t, func() bool { return q.NumPending() == 1 }, time.Second, time.Millisecond)
+		assert.NoError(t, job.Cancel())
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobCancelled)
+
+		close(release)
+		assert.Equal(t, 1, <-processed)
+
+		assert.Eventually(t, func() bool {
+			return mq.Len() == 0 && mq.Unacked() == 0
+		}, time.Second, time.Millisecond, "cancelled job should be skipped and acknowledged when it's dequeued")
+
+		q.WaitUntilFinished()
+		assert.Len(t, processed, 0, "cancelled job should not be processed")
+	})
+}
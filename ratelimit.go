@@ -0,0 +1,230 @@
+package varmq
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimiter limits how many jobs the worker dispatches over time, regardless of the concurrency.
+// The worker asks for the delay before dequeuing a job, and takes the cost of the job once it's dequeued,
+// so a limiter might run into debt by an expensive job, those delays the next jobs until it's paid off.
+type RateLimiter interface {
+	// Delay returns how long to wait until the next job can be dispatched, 0 if it can be dispatched now.
+	Delay() time.Duration
+	// Take takes the given cost of a dispatched job.
+	Take(cost int)
+}
+
+// tokenBucket refills n tokens per interval up to the burst size, a job is dispatched while there is a token
+type tokenBucket struct {
+	rate   float64 // tokens per nanosecond
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	mx     sync.Mutex
+}
+
+// NewTokenBucket creates a rate limiter those allows n jobs per interval on average,
+// and up to burst jobs at once after an idle period. The bucket starts full.
+func NewTokenBucket(n int, per time.Duration, burst int) RateLimiter {
+	n, burst = max(n, 1), max(burst, 1)
+
+	return &tokenBucket{
+		rate:   float64(n) / float64(max(per, 1)),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// clone returns a full bucket with the same rate and burst, see Copy
+func (b *tokenBucket) clone() RateLimiter {
+	return &tokenBucket{rate: b.rate, burst: b.burst, tokens: b.burst, last: b.now(), now: b.now}
+}
+
+// refill adds the tokens accumulated since the last refill, it must be called with the lock held
+func (b *tokenBucket) refill() time.Time {
+	now := b.now()
+
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+float64(now.Sub(b.last))*b.rate)
+	}
+
+	b.last = now
+	return now
+}
+
+func (b *tokenBucket) Delay() time.Duration {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.refill()
+
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate)
+}
+
+// idle reports whether the bucket is full again, see keyLimits
+func (b *tokenBucket) idle() bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.refill()
+	return b.tokens >= b.burst
+}
+
+func (b *tokenBucket) Take(cost int) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.refill()
+	b.tokens -= float64(cost)
+}
+
+// slidingWindow dispatches a job while the cost taken within the last window is less than n
+type slidingWindow struct {
+	n      int
+	window time.Duration
+	// taken are the costs taken within the window, ordered by time
+	taken []windowEntry
+	total int
+	now   func() time.Time
+	mx    sync.Mutex
+}
+
+type windowEntry struct {
+	at   time.Time
+	cost int
+}
+
+// NewSlidingWindow creates a rate limiter those allows n jobs within any window of the given duration.
+// Unlike a token bucket, it never allows a burst above n.
+func NewSlidingWindow(n int, window time.Duration) RateLimiter {
+	return &slidingWindow{
+		n:      max(n, 1),
+		window: max(window, 1),
+		now:    time.Now,
+	}
+}
+
+// clone returns an empty window with the same limit, see Copy
+func (w *slidingWindow) clone() RateLimiter {
+	return &slidingWindow{n: w.n, window: w.window, now: w.now}
+}
+
+// evict removes the entries those are out of the window, it must be called with the lock held
+func (w *slidingWindow) evict() time.Time {
+	now := w.now()
+	i := 0
+
+	for ; i < len(w.taken) && !w.taken[i].at.Add(w.window).After(now); i++ {
+		w.total -= w.taken[i].cost
+	}
+
+	w.taken = w.taken[i:]
+	return now
+}
+
+func (w *slidingWindow) Delay() time.Duration {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	now := w.evict()
+
+	// wait until enough cost leaves the window to drop the total below n
+	excess := w.total - w.n
+
+	for _, e := range w.taken {
+		if excess < 0 {
+			break
+		}
+
+		excess -= e.cost
+
+		if excess < 0 {
+			return e.at.Add(w.window).Sub(now)
+		}
+	}
+
+	return 0
+}
+
+// idle reports whether nothing is taken within the window, see keyLimits
+func (w *slidingWindow) idle() bool {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	w.evict()
+	return len(w.taken) == 0
+}
+
+func (w *slidingWindow) Take(cost int) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	now := w.evict()
+	w.taken = append(w.taken, windowEntry{at: now, cost: cost})
+	w.total += cost
+}
+
+// WithRateLimit limits the worker to dispatch n jobs per interval, with a burst of n jobs.
+// Use WithJobCost to make a job take more than one token, and WithRateLimiter for a custom burst or limiter.
+func WithRateLimit(n int, per time.Duration) ConfigFunc {
+	return WithRateLimiter(NewTokenBucket(n, per, n))
+}
+
+// WithRateLimiter limits how many jobs the worker dispatches over time by the given limiter,
+// e.g. NewTokenBucket or NewSlidingWindow. The copies of the worker get their own limiter made by these constructors,
+// while a custom limiter is shared by them unless a new one is passed to Copy.
+func WithRateLimiter(limiter RateLimiter) ConfigFunc {
+	return func(c *configs) {
+		c.RateLimiter = limiter
+	}
+}
+
+// rateWaker wakes up the event loop once the rate limit allows the next job, with at most one pending timer
+type rateWaker struct {
+	pending atomic.Bool
+}
+
+func (r *rateWaker) wakeAfter(d time.Duration, wake func()) {
+	if !r.pending.CompareAndSwap(false, true) {
+		return
+	}
+
+	time.AfterFunc(d, func() {
+		r.pending.Store(false)
+		wake()
+	})
+}
+
+// allowedByRateLimit reports whether the next job can be dispatched now by the rate limit of the worker.
+// If not, the event loop is notified once it's allowed.
+func (w *worker[T, R]) allowedByRateLimit() bool {
+	limiter := w.configs.RateLimiter
+
+	if limiter == nil {
+		return true
+	}
+
+	delay := limiter.Delay()
+
+	if delay <= 0 {
+		return true
+	}
+
+	w.rateWaker.wakeAfter(delay, w.notifyToPullNextJobs)
+	return false
+}
+
+// takeRateLimit takes the cost of the dispatched job from the rate limit of the worker
+func (w *worker[T, R]) takeRateLimit(j iJob[T, R]) {
+	if limiter := w.configs.RateLimiter; limiter != nil {
+		limiter.Take(j.Cost())
+	}
+}
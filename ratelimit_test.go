@@ -0,0 +1,155 @@
+package varmq
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock for the rate limiters
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestTokenBucket(t *testing.T) {
+	newBucket := func(n int, per time.Duration, burst int) (*tokenBucket, *fakeClock) {
+		clock := &fakeClock{t: time.Now()}
+		b := NewTokenBucket(n, per, burst).(*tokenBucket)
+		b.now = clock.now
+
+		return b, clock
+	}
+
+	t.Run("allows a burst then refills at the rate", func(t *testing.T) {
+		b, clock := newBucket(10, time.Second, 3)
+
+		for range 3 {
+			assert.Zero(t, b.Delay())
+			b.Take(1)
+		}
+
+		assert.Equal(t, 100*time.Millisecond, b.Delay())
+
+		clock.advance(100 * time.Millisecond)
+		assert.Zero(t, b.Delay())
+		b.Take(1)
+
+		clock.advance(time.Hour)
+		b.Delay()
+		assert.Equal(t, 3.0, b.tokens, "tokens should not exceed the burst")
+	})
+
+	t.Run("expensive jobs put it into debt", func(t *testing.T) {
+		b, clock := newBucket(10, time.Second, 1)
+
+		assert.Zero(t, b.Delay())
+		b.Take(5)
+
+		assert.Equal(t, 500*time.Millisecond, b.Delay())
+		clock.advance(500 * time.Millisecond)
+		assert.Zero(t, b.Delay())
+	})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	newWindow := func(n int, window time.Duration) (*slidingWindow, *fakeClock) {
+		clock := &fakeClock{t: time.Now()}
+		w := NewSlidingWindow(n, window).(*slidingWindow)
+		w.now = clock.now
+
+		return w, clock
+	}
+
+	t.Run("allows n jobs within the window", func(t *testing.T) {
+		w, clock := newWindow(2, time.Second)
+
+		assert.Zero(t, w.Delay())
+		w.Take(1)
+		clock.advance(300 * time.Millisecond)
+
+		assert.Zero(t, w.Delay())
+		w.Take(1)
+
+		assert.Equal(t, 700*time.Millisecond, w.Delay(), "should wait until the first job leaves the window")
+
+		clock.advance(700 * time.Millisecond)
+		assert.Zero(t, w.Delay())
+		w.Take(1)
+
+		assert.Equal(t, 300*time.Millisecond, w.Delay())
+	})
+
+	t.Run("waits until the debt leaves the window", func(t *testing.T) {
+		w, clock := newWindow(2, time.Second)
+
+		w.Take(1)
+		clock.advance(100 * time.Millisecond)
+		w.Take(3)
+
+		assert.Equal(t, time.Second, w.Delay(), "the expensive job should leave the window")
+		clock.advance(time.Second)
+		assert.Zero(t, w.Delay())
+		assert.Empty(t, w.taken)
+	})
+}
+
+func TestWorkerRateLimit(t *testing.T) {
+	t.Run("limits the dispatched jobs regardless of the concurrency", func(t *testing.T) {
+		var mx sync.Mutex
+		var started []time.Time
+
+		w := NewVoidWorker(func(_ int) {
+			mx.Lock()
+			started = append(started, time.Now())
+			mx.Unlock()
+		}, WithConcurrency(10), WithRateLimit(5, 100*time.Millisecond))
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		begin := time.Now()
+		jobs := make([]EnqueuedJob[any], 0, 10)
+		for i := range 10 {
+			job, _ := q.Add(i)
+			jobs = append(jobs, job)
+		}
+
+		for _, job := range jobs {
+			job.Result()
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+
+		assert.Len(t, started, 10)
+		assert.GreaterOrEqual(t, started[9].Sub(begin), 90*time.Millisecond, "the jobs above the burst should wait for the refill")
+	})
+
+	t.Run("takes the cost of the jobs", func(t *testing.T) {
+		w := NewVoidWorker(func(_ int) {}, WithRateLimiter(NewSlidingWindow(2, time.Hour)))
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		cheap, _ := q.Add(1)
+		expensive, _ := q.Add(2, WithJobCost(2))
+		blocked, _ := q.Add(3)
+
+		cheap.Result()
+		expensive.Result()
+
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, "Queued", blocked.Status(), "the limit is exhausted by the expensive job")
+		assert.Equal(t, 1, q.NumPending())
+	})
+}
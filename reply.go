@@ -0,0 +1,111 @@
+package varmq
+
+import (
+	"sync"
+)
+
+// ReplyQueueOpener opens the reply queue of the given address, see WithReplyQueues.
+type ReplyQueueOpener func(address string) (IQueue, error)
+
+// WithReplyQueues lets the worker reply the results of the jobs added by AddAndWait of a distributed queue.
+// Once such a job is finished, its result is enqueued into the reply queue of the address the producer has given,
+// see WithReplyQueue. The queues are opened once per address by the given function and reused afterwards.
+func WithReplyQueues(open ReplyQueueOpener) ConfigFunc {
+	return func(c *configs) {
+		c.ReplyQueues = open
+	}
+}
+
+// replyQueues caches the reply queues opened by the worker by their addresses
+type replyQueues struct {
+	queues map[string]IQueue
+	mx     sync.Mutex
+}
+
+func (r *replyQueues) get(address string, open ReplyQueueOpener) (IQueue, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if q, ok := r.queues[address]; ok {
+		return q, nil
+	}
+
+	q, err := open(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.queues == nil {
+		r.queues = make(map[string]IQueue)
+	}
+
+	r.queues[address] = q
+
+	return q, nil
+}
+
+// reply enqueues the result of the closed job into the reply queue of its producer if it's waiting for one
+func (w *worker[T, R]) reply(j iJob[T, R]) {
+	address := j.ReplyTo()
+	open := w.configs.ReplyQueues
+
+	if address == "" || open == nil {
+		return
+	}
+
+	result, err := encodeResult(j, w.configs.Codec)
+	if err != nil {
+		return
+	}
+
+	q, err := w.replies.get(address, open)
+	if err != nil {
+		return
+	}
+
+	q.Enqueue(result)
+}
+
+// replyWaiters holds the producer calls waiting for the replies by the correlation ids
+type replyWaiters[R any] struct {
+	waiting map[string]chan Result[R]
+	mx      sync.Mutex
+}
+
+// wait registers the correlation id, it must be called before the job is enqueued so its reply is never missed
+func (r *replyWaiters[R]) wait(id string) (<-chan Result[R], bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if _, ok := r.waiting[id]; ok {
+		return nil, false
+	}
+
+	if r.waiting == nil {
+		r.waiting = make(map[string]chan Result[R])
+	}
+
+	ch := make(chan Result[R], 1)
+	r.waiting[id] = ch
+
+	return ch, true
+}
+
+func (r *replyWaiters[R]) forget(id string) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	delete(r.waiting, id)
+}
+
+// deliver sends the reply to the call waiting for it, the replies nobody waits for anymore are dropped
+func (r *replyWaiters[R]) deliver(reply Result[R]) {
+	r.mx.Lock()
+	ch, ok := r.waiting[reply.JobId]
+	delete(r.waiting, reply.JobId)
+	r.mx.Unlock()
+
+	if ok {
+		ch <- reply
+	}
+}
@@ -0,0 +1,98 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplyQueue(t *testing.T) {
+	t.Run("AddAndWait resolves with the result replied by the consumer", func(t *testing.T) {
+		errOdd := errors.New("odd")
+		jobs := newMockDistributedQueue()
+		replies := newMockDistributedQueue()
+
+		// the consumer might live in another process, opening the reply queue by its address
+		consumer := NewWorker(func(data int) (int, error) {
+			if data%2 == 1 {
+				return 0, errOdd
+			}
+
+			return data * 2, nil
+		}, WithReplyQueues(func(address string) (IQueue, error) {
+			assert.Equal(t, "replies", address)
+			return replies, nil
+		})).WithDistributedQueue(jobs)
+		defer consumer.Close()
+
+		producer := NewDistributedQueue[int, int](jobs, WithReplyQueue("replies", replies))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		result, err := producer.AddAndWait(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+
+		_, err = producer.AddAndWait(ctx, 3, WithJobId("odd"))
+		assert.EqualError(t, err, errOdd.Error())
+
+		assert.Equal(t, 0, replies.Len(), "the replies should be acknowledged")
+	})
+
+	t.Run("Add doesn't wait for a reply", func(t *testing.T) {
+		jobs := newMockDistributedQueue()
+		replies := newMockDistributedQueue()
+
+		consumer := NewWorker(func(data int) (int, error) {
+			return data, nil
+		}, WithReplyQueues(func(address string) (IQueue, error) {
+			return replies, nil
+		})).WithDistributedQueue(jobs)
+
+		producer := NewDistributedQueue[int, int](jobs, WithReplyQueue("replies", replies))
+		assert.True(t, producer.Add(1, WithJobId("job")))
+
+		defer consumer.Close()
+
+		assert.Eventually(t, func() bool {
+			return jobs.Len() == 0 && jobs.Unacked() == 0
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, 0, replies.Len())
+	})
+
+	t.Run("AddAndWait stops waiting once the context is done", func(t *testing.T) {
+		producer := NewDistributedQueue[int, int](newMockDistributedQueue(), WithReplyQueue("replies", newMockDistributedQueue()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := producer.AddAndWait(ctx, 1, WithJobId("job"))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// the id is free to wait for again
+		_, err = producer.AddAndWait(ctxDone(), 1, WithJobId("job"))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("reply address of a queued job is persisted", func(t *testing.T) {
+		j := newJob[int, int](1, loadJobConfigs(newConfig(), WithJobId("job"), withReplyTo("replies")))
+
+		data, err := j.encode(nil)
+		assert.NoError(t, err)
+
+		parsed, err := parseToJob[int, int](data, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "replies", parsed.ReplyTo())
+	})
+
+	t.Run("AddAndWait requires a reply queue", func(t *testing.T) {
+		producer := NewDistributedQueue[int, int](newMockDistributedQueue())
+
+		_, err := producer.AddAndWait(context.Background(), 1)
+		assert.ErrorIs(t, err, errNoReplyQueue)
+	})
+}
@@ -8,12 +8,12 @@ type Result[T any] struct {
 }
 
 // EnqueuedJob represents a job that has been enqueued and can wait for a result.
+// Cancelling a pending job removes it from the queue, while a running job gets its context cancelled.
 type EnqueuedJob[R any] interface {
 	Job
+	Future[R]
 	// Drain discards the job's result and error values asynchronously.
 	Drain() error
-	// Result blocks until the job completes and returns the result and any error.
-	Result() (R, error)
 }
 
 type EnqueuedGroupJob[T any] interface {
@@ -25,6 +25,9 @@ type EnqueuedGroupJob[T any] interface {
 	Drain() error
 	// Results returns a channel that will receive the results of the group
 	Results() (<-chan Result[T], error)
+	// Cancel cancels all the pending and running jobs of the group.
+	// It returns an error if there is no job left to cancel.
+	Cancel() error
 }
 
 type EnqueuedSingleGroupJob[R any] interface {
@@ -0,0 +1,292 @@
+package varmq
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// memoryBackendSweep is the number of the stores those the expired results of a memory backend are swept after
+const memoryBackendSweep = 1024
+
+// WithResultBackend stores the result of every finished job with an id into the given backend, and keeps it for the given ttl,
+// forever if it's zero. It lets the workers with results be bound to distributed queues, and the producers of another
+// process fetch the results by the job ids, see ResultOf.
+func WithResultBackend(backend IResultBackend, ttl time.Duration) ConfigFunc {
+	return func(c *configs) {
+		c.ResultBackend = backend
+		c.ResultTTL = max(ttl, 0)
+	}
+}
+
+// storedResult is the serialized result of a job in a result backend
+type storedResult[R any] struct {
+	JobId string `json:"job_id"`
+	Data  R      `json:"data"`
+	// Error is the text of the error the job failed with, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+	// ErrorCode restores the errors of varmq, e.g. ErrJobTimeout, so errors.Is keeps working, see errorView.
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// parseStoredResult decodes the result encoded by encodeResult with the given codec or a built-in one, see decodePayload
+func parseStoredResult[R any](data []byte, codec Codec) (Result[R], error) {
+	var view storedResult[R]
+
+	if err := decodePayload(codec, data, &view); err != nil {
+		return Result[R]{}, fmt.Errorf("failed to parse result: %w", err)
+	}
+
+	e := &errorView{Message: view.Error, Code: view.ErrorCode}
+
+	return Result[R]{JobId: view.JobId, Data: view.Data, Err: e.error()}, nil
+}
+
+// storeResult stores the result of the closed job into the result backend if configured
+func (w *worker[T, R]) storeResult(j iJob[T, R]) {
+	backend := w.configs.ResultBackend
+	id := j.ID()
+
+	if backend == nil || id == "" {
+		return
+	}
+
+	result, err := encodeResult(j, w.configs.Codec)
+	if err != nil {
+		return
+	}
+
+	backend.Store(id, result, w.configs.ResultTTL)
+}
+
+// encodeResult serializes the outcome of the closed job as a storedResult with the given codec, see encodePayload
+func encodeResult[T, R any](j iJob[T, R], codec Codec) ([]byte, error) {
+	view := storedResult[R]{JobId: j.ID()}
+	data, err := j.outcomeResult()
+
+	if e := newErrorView(err); e != nil {
+		view.Error = e.Message
+		view.ErrorCode = e.Code
+	} else {
+		view.Data = data
+	}
+
+	return encodePayload(codec, view)
+}
+
+// ResultOf returns a future of the result of the job with the given id stored in the given backend,
+// e.g. to wait for a job of a distributed queue from the producer process.
+// The future stops waiting with the context error once the context is done. It can't be cancelled,
+// since the job might be processed by another process.
+// The result is decoded by the built-in codec it's encoded with, see ResultOfCodec for a custom one.
+func ResultOf[R any](ctx context.Context, backend IResultBackend, id string) Future[R] {
+	return ResultOfCodec[R](ctx, backend, id, nil)
+}
+
+// ResultOfCodec works like ResultOf, and decodes the results encoded by the given codec as well,
+// those the worker is configured with by WithCodec.
+func ResultOfCodec[R any](ctx context.Context, backend IResultBackend, id string, codec Codec) Future[R] {
+	f := newFuture[R](nil)
+
+	unsubscribe := backend.Subscribe(id, func(data []byte) {
+		r, err := parseStoredResult[R](data, codec)
+		if err != nil {
+			r = Result[R]{JobId: id, Err: err}
+		}
+
+		f.resolve(r)
+	})
+
+	stop := context.AfterFunc(ctx, func() {
+		f.resolve(Result[R]{JobId: id, Err: ctx.Err()})
+	})
+
+	f.watch(func(Result[R]) {
+		stop()
+		unsubscribe()
+	})
+
+	return f
+}
+
+// resultSubscribers holds the functions waiting for the results of the jobs, each of them is called once
+type resultSubscribers struct {
+	next uint64
+	subs map[string]map[uint64]func([]byte)
+	mx   sync.Mutex
+}
+
+func (s *resultSubscribers) add(id string, fn func([]byte)) uint64 {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.subs == nil {
+		s.subs = make(map[string]map[uint64]func([]byte))
+	}
+
+	if s.subs[id] == nil {
+		s.subs[id] = make(map[uint64]func([]byte))
+	}
+
+	s.next++
+	s.subs[id][s.next] = fn
+
+	return s.next
+}
+
+// remove removes the subscriber and returns its function, false if it's already removed or notified
+func (s *resultSubscribers) remove(id string, key uint64) (func([]byte), bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	fn, ok := s.subs[id][key]
+	if !ok {
+		return nil, false
+	}
+
+	delete(s.subs[id], key)
+	if len(s.subs[id]) == 0 {
+		delete(s.subs, id)
+	}
+
+	return fn, true
+}
+
+// has reports whether the subscriber is still waiting
+func (s *resultSubscribers) has(id string, key uint64) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	_, ok := s.subs[id][key]
+	return ok
+}
+
+// notify calls and removes all the subscribers of the job
+func (s *resultSubscribers) notify(id string, result []byte) {
+	s.mx.Lock()
+	subs := s.subs[id]
+	delete(s.subs, id)
+	s.mx.Unlock()
+
+	for _, fn := range subs {
+		fn(result)
+	}
+}
+
+// subscribe registers the function and calls it right away if the result is already stored by fetch.
+// It's registered before fetching, so a result stored in between is never missed.
+func (s *resultSubscribers) subscribe(id string, fn func([]byte), fetch func() ([]byte, bool)) (uint64, bool) {
+	key := s.add(id, fn)
+
+	if result, ok := fetch(); ok {
+		if fn, ok := s.remove(id, key); ok {
+			fn(result)
+		}
+
+		return key, true
+	}
+
+	return key, false
+}
+
+// MemoryResultBackend is an IResultBackend keeping the results in memory, e.g. for tests or a single process.
+// The expired results are removed once they're fetched, or swept while storing the new ones.
+type MemoryResultBackend struct {
+	results     map[string]storedEntry
+	stores      int
+	subscribers resultSubscribers
+	mx          sync.Mutex
+}
+
+// storedEntry is a result kept by a backend until it expires
+type storedEntry struct {
+	Result    []byte     `json:"result"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func newStoredEntry(result []byte, ttl time.Duration) storedEntry {
+	e := storedEntry{Result: slices.Clone(result)}
+
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		e.ExpiresAt = &expiresAt
+	}
+
+	return e
+}
+
+func (e storedEntry) expired(now time.Time) bool {
+	return e.ExpiresAt != nil && now.After(*e.ExpiresAt)
+}
+
+// NewMemoryResultBackend creates an empty in-memory result backend.
+func NewMemoryResultBackend() *MemoryResultBackend {
+	return &MemoryResultBackend{
+		results: make(map[string]storedEntry),
+	}
+}
+
+func (b *MemoryResultBackend) Store(id string, result []byte, ttl time.Duration) error {
+	e := newStoredEntry(result, ttl)
+
+	b.mx.Lock()
+	b.results[id] = e
+
+	if b.stores++; b.stores%memoryBackendSweep == 0 {
+		b.sweep()
+	}
+	b.mx.Unlock()
+
+	b.subscribers.notify(id, e.Result)
+
+	return nil
+}
+
+// sweep removes the expired results, it must be called with the lock held
+func (b *MemoryResultBackend) sweep() {
+	now := time.Now()
+
+	for id, e := range b.results {
+		if e.expired(now) {
+			delete(b.results, id)
+		}
+	}
+}
+
+func (b *MemoryResultBackend) Fetch(id string) ([]byte, bool, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	e, ok := b.results[id]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if e.expired(time.Now()) {
+		delete(b.results, id)
+		return nil, false, nil
+	}
+
+	return e.Result, true, nil
+}
+
+func (b *MemoryResultBackend) Subscribe(id string, fn func(result []byte)) func() {
+	key, _ := b.subscribers.subscribe(id, fn, func() ([]byte, bool) {
+		result, ok, _ := b.Fetch(id)
+		return result, ok
+	})
+
+	return func() {
+		b.subscribers.remove(id, key)
+	}
+}
+
+// Len returns the number of the stored results, the expired ones those are not removed yet included.
+func (b *MemoryResultBackend) Len() int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	return len(b.results)
+}
@@ -0,0 +1,151 @@
+package varmq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileBackendPollInterval is how often a file result backend looks for the results stored by the other processes
+const fileBackendPollInterval = 100 * time.Millisecond
+
+// FileResultBackend is an IResultBackend keeping each result in a JSON file of a directory,
+// so the processes sharing the directory can fetch the results of each other.
+// The expired results are removed once they're fetched.
+type FileResultBackend struct {
+	dir         string
+	subscribers resultSubscribers
+}
+
+// NewFileResultBackend creates a result backend storing the results into the given directory, creating it if it doesn't exist.
+func NewFileResultBackend(dir string) (*FileResultBackend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create result directory: %w", err)
+	}
+
+	return &FileResultBackend{dir: dir}, nil
+}
+
+// path returns the file of the result, the id is escaped since it might contain slashes, e.g. the ids of workflow steps
+func (b *FileResultBackend) path(id string) string {
+	return filepath.Join(b.dir, url.PathEscape(id)+".json")
+}
+
+func (b *FileResultBackend) Store(id string, result []byte, ttl time.Duration) error {
+	if id == "" {
+		return errors.New("job id is required to store a result")
+	}
+
+	e := newStoredEntry(result, ttl)
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to serialize result: %w", err)
+	}
+
+	// written into a temporary file and renamed, so a half written result is never fetched
+	tmp, err := os.CreateTemp(b.dir, ".result-*")
+	if err != nil {
+		return fmt.Errorf("failed to store result: %w", err)
+	}
+
+	_, err = tmp.Write(data)
+	err = errors.Join(err, tmp.Close())
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), b.path(id))
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store result: %w", err)
+	}
+
+	b.subscribers.notify(id, e.Result)
+
+	return nil
+}
+
+func (b *FileResultBackend) Fetch(id string) ([]byte, bool, error) {
+	if id == "" {
+		return nil, false, nil
+	}
+
+	data, err := os.ReadFile(b.path(id))
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch result: %w", err)
+	}
+
+	var e storedEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, false, fmt.Errorf("failed to parse result: %w", err)
+	}
+
+	if e.expired(time.Now()) {
+		os.Remove(b.path(id))
+		return nil, false, nil
+	}
+
+	return e.Result, true, nil
+}
+
+// Subscribe calls the given function once the result is stored. The results stored by this backend are notified
+// right away, while the ones stored by the other processes are polled for.
+func (b *FileResultBackend) Subscribe(id string, fn func(result []byte)) func() {
+	fetch := func() ([]byte, bool) {
+		result, ok, _ := b.Fetch(id)
+		return result, ok
+	}
+
+	key, done := b.subscribers.subscribe(id, fn, fetch)
+	if done {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(fileBackendPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			// notified by a store of this process
+			if !b.subscribers.has(id, key) {
+				return
+			}
+
+			result, ok := fetch()
+			if !ok {
+				continue
+			}
+
+			if fn, ok := b.subscribers.remove(id, key); ok {
+				fn(result)
+			}
+
+			return
+		}
+	}()
+
+	return func() {
+		if _, ok := b.subscribers.remove(id, key); ok {
+			close(stop)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockDistributedQueue is an in-memory implementation of IDistributedQueue for testing
+type mockDistributedQueue struct {
+	*mockPersistentQueue
+	subscriber func(action string)
+	smx        sync.Mutex
+}
+
+func newMockDistributedQueue() *mockDistributedQueue {
+	return &mockDistributedQueue{mockPersistentQueue: newMockPersistentQueue()}
+}
+
+func (q *mockDistributedQueue) Subscribe(fn func(action string)) {
+	q.smx.Lock()
+	defer q.smx.Unlock()
+
+	q.subscriber = fn
+}
+
+func (q *mockDistributedQueue) Enqueue(item any) bool {
+	if !q.mockPersistentQueue.Enqueue(item) {
+		return false
+	}
+
+	q.smx.Lock()
+	fn := q.subscriber
+	q.smx.Unlock()
+
+	if fn != nil {
+		fn("enqueued")
+	}
+
+	return true
+}
+
+func TestResultBackend(t *testing.T) {
+	backends := map[string]func(t *testing.T) IResultBackend{
+		"memory": func(t *testing.T) IResultBackend {
+			return NewMemoryResultBackend()
+		},
+		"file": func(t *testing.T) IResultBackend {
+			b, err := NewFileResultBackend(t.TempDir())
+			assert.NoError(t, err)
+			return b
+		},
+	}
+
+	for name, newBackend := range backends {
+		t.Run(name+" stores and fetches results", func(t *testing.T) {
+			b := newBackend(t)
+
+			_, ok, err := b.Fetch("wf/1/step")
+			assert.NoError(t, err)
+			assert.False(t, ok)
+
+			assert.NoError(t, b.Store("wf/1/step", []byte(`"done"`), 0))
+
+			result, ok, err := b.Fetch("wf/1/step")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, `"done"`, string(result))
+		})
+
+		t.Run(name+" forgets expired results", func(t *testing.T) {
+			b := newBackend(t)
+
+			assert.NoError(t, b.Store("job", []byte(`1`), time.Millisecond))
+			time.Sleep(5 * time.Millisecond)
+
+			_, ok, err := b.Fetch("job")
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+
+		t.Run(name+" notifies subscribers once", func(t *testing.T) {
+			b := newBackend(t)
+			results := make(chan string, 3)
+
+			b.Subscribe("job", func(result []byte) {
+				results <- "before:" + string(result)
+			})
+			unsubscribe := b.Subscribe("job", func(result []byte) {
+				results <- "unsubscribed"
+			})
+			unsubscribe()
+
+			assert.NoError(t, b.Store("job", []byte(`1`), 0))
+			assert.NoError(t, b.Store("job", []byte(`2`), 0))
+
+			b.Subscribe("job", func(result []byte) {
+				results <- "after:" + string(result)
+			})
+
+			assert.Equal(t, "before:1", <-results)
+			assert.Equal(t, "after:2", <-results)
+			assert.Empty(t, results)
+		})
+	}
+
+	t.Run("file backends share the results of their processes", func(t *testing.T) {
+		dir := t.TempDir()
+		producer, err := NewFileResultBackend(dir)
+		assert.NoError(t, err)
+		consumer, err := NewFileResultBackend(dir)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		future := ResultOf[int](ctx, producer, "job")
+		assert.NoError(t, consumer.Store("job", []byte(`{"job_id":"job","data":4}`), 0))
+
+		result, err := future.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+	})
+
+	t.Run("worker with results is bound to a distributed queue", func(t *testing.T) {
+		backend := NewMemoryResultBackend()
+		errOdd := errors.New("odd")
+
+		dq := newMockDistributedQueue()
+		q := NewWorker(func(data int) (int, error) {
+			if data%2 == 1 {
+				return 0, errOdd
+			}
+
+			return data * 2, nil
+		}, WithResultBackend(backend, time.Minute)).WithDistributedQueue(dq)
+		defer q.Close()
+
+		// the producer might live in another process, sharing only the queue and the backend
+		producer := NewDistributedQueue[int, int](dq)
+		assert.True(t, producer.Add(2, WithJobId("even")))
+		assert.True(t, producer.Add(3, WithJobId("odd")))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		result, err := ResultOf[int](ctx, backend, "even").Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+
+		_, err = ResultOf[int](ctx, backend, "odd").Result()
+		assert.EqualError(t, err, errOdd.Error())
+	})
+
+	t.Run("persistent queue stores results", func(t *testing.T) {
+		backend := NewMemoryResultBackend()
+
+		q := NewWorker(func(data int) (int, error) {
+			return data * 2, nil
+		}, WithResultBackend(backend, 0)).WithPersistentQueue(newMockPersistentQueue())
+		defer q.Close()
+
+		job, _ := q.Add(2, WithJobId("job"))
+		_, err := job.Result()
+		assert.NoError(t, err)
+
+		result, err := ResultOf[int](context.Background(), backend, "job").Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+	})
+
+	t.Run("results are encoded by the codec of the worker", func(t *testing.T) {
+		backend := NewMemoryResultBackend()
+		codec := namedCodec("custom")
+
+		q := NewWorker(func(data int) (int, error) {
+			return data * 2, nil
+		}, WithResultBackend(backend, 0), WithCodec(codec)).WithPersistentQueue(newMockPersistentQueue())
+		defer q.Close()
+
+		job, _ := q.Add(2, WithJobId("job"))
+		_, err := job.Result()
+		assert.NoError(t, err)
+
+		_, err = ResultOf[int](context.Background(), backend, "job").Result()
+		assert.ErrorIs(t, err, errUnknownCodec)
+
+		result, err := ResultOfCodec[int](context.Background(), backend, "job", codec).Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 4, result)
+	})
+
+	t.Run("ResultOf stops waiting once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := ResultOf[int](ctx, NewMemoryResultBackend(), "missing").Result()
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
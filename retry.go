@@ -0,0 +1,96 @@
+package varmq
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// BackoffFunc returns the delay to wait before the given retry attempt.
+// The attempt starts from 1 for the first retry.
+type BackoffFunc func(attempt int) time.Duration
+
+// retryPolicy describes how many times a failed job will be attempted and how long to wait between attempts.
+type retryPolicy struct {
+	MaxAttempts int
+	Backoff     BackoffFunc
+	// restored is true if the policy is parsed from a persistent queue, so its backoff is not known
+	restored bool
+}
+
+// enabled returns true if the policy allows more than one attempt.
+func (p retryPolicy) enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// delay returns the backoff delay before the given retry attempt.
+func (p retryPolicy) delay(attempt int) time.Duration {
+	if p.Backoff == nil {
+		return 0
+	}
+
+	return max(p.Backoff(attempt), 0)
+}
+
+// WithRetry retries a job up to maxAttempts times (including the first attempt)
+// when the worker function returns an error or panics, waiting for the backoff delay between attempts.
+// If backoff is nil, the job is retried immediately.
+//
+// It can be passed to the worker constructors to apply it to every job of the worker,
+// or to Add to apply it to a single job. Jobs of persistent queues keep their max attempts,
+// but a backoff function can't be serialized, so they're retried by the backoff of the worker once they're restored.
+//
+// Example:
+//
+//	worker := NewWorker(fn, WithRetry(3, ExponentialBackoff(time.Second, time.Minute)))
+//	queue.Add(data, WithRetry(5, LinearBackoff(time.Second)))
+func WithRetry(maxAttempts int, backoff BackoffFunc) JobConfigFunc {
+	return func(c *jobConfigs) {
+		c.Retry = retryPolicy{
+			MaxAttempts: max(maxAttempts, 1),
+			Backoff:     backoff,
+		}
+	}
+}
+
+// ConstantBackoff waits the same delay before every retry.
+func ConstantBackoff(delay time.Duration) BackoffFunc {
+	return func(_ int) time.Duration {
+		return delay
+	}
+}
+
+// LinearBackoff waits base * attempt before every retry.
+func LinearBackoff(base time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		return base * time.Duration(attempt)
+	}
+}
+
+// ExponentialBackoff waits base * 2^(attempt-1) before every retry, capped to maxDelay.
+// If maxDelay is less than or equal to 0, the delay is not capped.
+func ExponentialBackoff(base, maxDelay time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		delay := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+
+		// handle overflow of the multiplication as well
+		if maxDelay > 0 && (delay > maxDelay || delay < 0) {
+			return maxDelay
+		}
+
+		return delay
+	}
+}
+
+// JitterBackoff randomizes the delay of the given backoff by ±factor of it.
+// The factor is clamped between 0 and 1, e.g. 0.2 means the delay will vary by ±20%.
+func JitterBackoff(backoff BackoffFunc, factor float64) BackoffFunc {
+	factor = min(max(factor, 0), 1)
+
+	return func(attempt int) time.Duration {
+		delay := float64(backoff(attempt))
+		spread := delay * factor
+
+		return time.Duration(delay - spread + rand.Float64()*2*spread)
+	}
+}
@@ -0,0 +1,190 @@
+package varmq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	t.Run("ConstantBackoff", func(t *testing.T) {
+		b := ConstantBackoff(time.Second)
+
+		assert.Equal(t, time.Second, b(1))
+		assert.Equal(t, time.Second, b(10))
+	})
+
+	t.Run("LinearBackoff", func(t *testing.T) {
+		b := LinearBackoff(time.Second)
+
+		assert.Equal(t, time.Second, b(1))
+		assert.Equal(t, 3*time.Second, b(3))
+	})
+
+	t.Run("ExponentialBackoff", func(t *testing.T) {
+		b := ExponentialBackoff(time.Second, 10*time.Second)
+
+		assert.Equal(t, time.Second, b(1))
+		assert.Equal(t, 2*time.Second, b(2))
+		assert.Equal(t, 8*time.Second, b(4))
+		assert.Equal(t, 10*time.Second, b(5), "delay should be capped to max delay")
+		assert.Equal(t, 10*time.Second, b(100), "overflowed delay should be capped to max delay")
+	})
+
+	t.Run("JitterBackoff", func(t *testing.T) {
+		b := JitterBackoff(ConstantBackoff(time.Second), 0.2)
+
+		for range 100 {
+			delay := b(1)
+			assert.GreaterOrEqual(t, delay, 800*time.Millisecond)
+			assert.LessOrEqual(t, delay, 1200*time.Millisecond)
+		}
+	})
+
+	t.Run("retry policy never returns negative delay", func(t *testing.T) {
+		p := retryPolicy{MaxAttempts: 2, Backoff: ConstantBackoff(-time.Second)}
+		assert.Equal(t, time.Duration(0), p.delay(1))
+
+		p = retryPolicy{MaxAttempts: 2}
+		assert.Equal(t, time.Duration(0), p.delay(1), "nil backoff should retry immediately")
+	})
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("WithRetry as worker config is applied to every job", func(t *testing.T) {
+		c := loadConfigs(WithRetry(3, nil))
+		jc := loadJobConfigs(c)
+
+		assert.Equal(t, 3, jc.Retry.MaxAttempts)
+	})
+
+	t.Run("WithRetry as job config overrides the worker config", func(t *testing.T) {
+		c := loadConfigs(WithRetry(3, nil))
+		jc := loadJobConfigs(c, WithRetry(5, nil))
+
+		assert.Equal(t, 5, jc.Retry.MaxAttempts)
+	})
+
+	t.Run("failed job is retried until it succeeds", func(t *testing.T) {
+		var calls atomic.Int32
+
+		q := NewWorker(func(data int) (int, error) {
+			if calls.Add(1) < 3 {
+				return 0, errors.New("temporary error")
+			}
+
+			return data * 2, nil
+		}, WithRetry(3, ConstantBackoff(10*time.Millisecond))).BindQueue()
+		defer q.Close()
+
+		job, ok := q.Add(21)
+		assert.True(t, ok)
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 42, result)
+		assert.Equal(t, int32(3), calls.Load())
+
+		data, err := job.Json()
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"attempts":3`)
+	})
+
+	t.Run("panicked job is retried", func(t *testing.T) {
+		var calls atomic.Int32
+
+		q := NewErrWorker(func(_ int) error {
+			if calls.Add(1) == 1 {
+				panic("boom")
+			}
+
+			return nil
+		}, WithRetry(2, nil)).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1)
+		_, err := job.Result()
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("last error is sent when attempts are exhausted", func(t *testing.T) {
+		var calls atomic.Int32
+		expectedErr := errors.New("permanent error")
+
+		q := NewWorker(func(_ int) (int, error) {
+			calls.Add(1)
+			return 0, expectedErr
+		}).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1, WithRetry(4, LinearBackoff(time.Millisecond)))
+		_, err := job.Result()
+
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Equal(t, int32(4), calls.Load())
+	})
+
+	t.Run("attempt is visible to context-aware worker", func(t *testing.T) {
+		var attempts []int
+
+		q := NewWorkerCtx(func(ctx context.Context, _ int) (int, error) {
+			attempt := AttemptFromContext(ctx)
+			attempts = append(attempts, attempt)
+
+			if attempt < 2 {
+				return 0, errors.New("try again")
+			}
+
+			return attempt, nil
+		}, WithRetry(2, nil)).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1)
+		result, err := job.Result()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result)
+		assert.Equal(t, []int{1, 2}, attempts)
+	})
+
+	t.Run("WaitUntilFinished waits for delayed retries", func(t *testing.T) {
+		var calls atomic.Int32
+
+		q := NewVoidWorker(func(_ int) {
+			if calls.Add(1) == 1 {
+				panic("boom")
+			}
+		}, WithRetry(2, ConstantBackoff(50*time.Millisecond))).BindQueue()
+
+		q.Add(1)
+		q.WaitUntilFinished()
+
+		assert.Equal(t, int32(2), calls.Load())
+		q.Close()
+	})
+
+	t.Run("retried job keeps its priority", func(t *testing.T) {
+		var calls atomic.Int32
+
+		q := NewWorker(func(data int) (int, error) {
+			if calls.Add(1) == 1 {
+				return 0, errors.New("temporary error")
+			}
+
+			return data, nil
+		}, WithRetry(2, nil)).BindPriorityQueue()
+		defer q.Close()
+
+		job, _ := q.Add(7, 3)
+		result, err := job.Result()
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, result)
+	})
+}
@@ -0,0 +1,405 @@
+package varmq
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/goptics/varmq/utils"
+)
+
+// OverlapPolicy decides what happens when a scheduled run is due while the job of the previous run is still running.
+type OverlapPolicy uint8
+
+const (
+	// OverlapAllow enqueues the job regardless of the previous one, so they might run concurrently.
+	OverlapAllow OverlapPolicy = iota
+	// OverlapSkip skips the run if the job of the previous run is still running.
+	OverlapSkip
+	// OverlapQueue delays the run until the job of the previous run is finished.
+	// Multiple delayed runs are coalesced into one.
+	OverlapQueue
+)
+
+var (
+	errScheduleExists   = errors.New("schedule already exists")
+	errSchedulerRunning = errors.New("scheduler is already running")
+	errNoJobIdGenerator = errors.New("job id generator is required to schedule jobs into persistent queues")
+)
+
+// ScheduledTask enqueues the job of a scheduled run, see QueueTask, PriorityQueueTask and TaskFunc.
+type ScheduledTask interface {
+	// Run enqueues the job and returns a function to wait until the job is finished and whether the job is enqueued successfully.
+	Run() (wait func(), ok bool)
+}
+
+// TaskFunc is a ScheduledTask those enqueues the job of a scheduled run by calling the function.
+type TaskFunc func() (wait func(), ok bool)
+
+func (f TaskFunc) Run() (func(), bool) {
+	return f()
+}
+
+// jobIdRequirer is implemented by the queues those require an id for every job, e.g. persistent queues.
+// requireJobId returns an error if the jobs added without an id don't get one.
+type jobIdRequirer interface {
+	requireJobId() error
+}
+
+// queueTask is a ScheduledTask those adds a job into a queue on every run
+type queueTask struct {
+	run func() (func(), bool)
+	// err is returned by Schedule, since the task can't add any job, e.g. a persistent queue without an id generator
+	err error
+}
+
+func (t *queueTask) Run() (func(), bool) {
+	return t.run()
+}
+
+// newQueueTask returns a queueTask those runs the given function, the queue is checked whether it can add the jobs
+func newQueueTask(q any, configs []JobConfigFunc, run func() (func(), bool)) *queueTask {
+	t := &queueTask{run: run}
+
+	r, ok := q.(jobIdRequirer)
+	if !ok {
+		return t
+	}
+
+	// a fixed id set by the configs is enough
+	var c jobConfigs
+	for _, config := range configs {
+		config(&c)
+	}
+
+	if c.Id == "" {
+		t.err = r.requireJobId()
+	}
+
+	return t
+}
+
+// QueueTask returns a ScheduledTask those add the data into the queue on every run.
+// It works with persistent queues as well, use WithJobIdGenerator on the worker to generate unique job ids for them,
+// otherwise Schedule returns an error.
+//
+// Example:
+//
+//	scheduler.ScheduleCron("reconcile", "0 2 * * *", QueueTask(queue, "accounts"))
+func QueueTask[T, R any](q Queue[T, R], data T, configs ...JobConfigFunc) ScheduledTask {
+	return newQueueTask(q, configs, func() (func(), bool) {
+		j, ok := q.Add(data, configs...)
+		if !ok {
+			return nil, false
+		}
+
+		return func() { j.Result() }, true
+	})
+}
+
+// PriorityQueueTask returns a ScheduledTask those add the data with the given priority into the priority queue on every run.
+func PriorityQueueTask[T, R any](q PriorityQueue[T, R], data T, priority int, configs ...JobConfigFunc) ScheduledTask {
+	return newQueueTask(q, configs, func() (func(), bool) {
+		j, ok := q.Add(data, priority, configs...)
+		if !ok {
+			return nil, false
+		}
+
+		return func() { j.Result() }, true
+	})
+}
+
+type ScheduleConfigFunc func(*scheduleConfigs)
+
+type scheduleConfigs struct {
+	Overlap OverlapPolicy
+	Jitter  time.Duration
+}
+
+// WithOverlapPolicy sets what happens when a run is due while the previous job is still running.
+// Default is OverlapAllow.
+func WithOverlapPolicy(policy OverlapPolicy) ScheduleConfigFunc {
+	return func(c *scheduleConfigs) {
+		c.Overlap = policy
+	}
+}
+
+// WithJitter delays every run by a random duration between 0 and the given jitter,
+// to avoid many schedules hitting the queue at the very same moment.
+func WithJitter(jitter time.Duration) ScheduleConfigFunc {
+	return func(c *scheduleConfigs) {
+		c.Jitter = max(jitter, 0)
+	}
+}
+
+// scheduleEntry is a single schedule registered in the scheduler
+type scheduleEntry struct {
+	schedule Schedule
+	task     ScheduledTask
+	configs  scheduleConfigs
+	stop     chan struct{}
+	running  int
+	pending  bool
+	stopped  bool
+	mx       sync.Mutex
+}
+
+// Scheduler enqueues jobs into bound queues on recurring schedules,
+// either by standard cron expressions or fixed intervals.
+type Scheduler struct {
+	entries map[string]*scheduleEntry
+	running bool
+	wg      sync.WaitGroup
+	mx      sync.Mutex
+}
+
+// NewScheduler creates a new scheduler, it doesn't run any schedule until Start is called.
+//
+// Example:
+//
+//	scheduler := NewScheduler()
+//	scheduler.ScheduleCron("nightly", "0 2 * * *", QueueTask(queue, "reconcile"), WithOverlapPolicy(OverlapSkip))
+//	scheduler.Schedule("heartbeat", Every(time.Minute), QueueTask(queue, "ping"), WithJitter(5*time.Second))
+//	scheduler.Start()
+//	defer scheduler.Stop()
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		entries: make(map[string]*scheduleEntry),
+	}
+}
+
+// Schedule registers the task to be run on the given schedule with the given name.
+// It returns an error if a schedule with the same name already exists, or the task can't add any job,
+// e.g. a QueueTask of a persistent queue without a job id generator.
+func (s *Scheduler) Schedule(name string, schedule Schedule, task ScheduledTask, configs ...ScheduleConfigFunc) error {
+	if t, ok := task.(*queueTask); ok && t.err != nil {
+		return t.err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.entries[name]; ok {
+		return fmt.Errorf("%w: %s", errScheduleExists, name)
+	}
+
+	e := &scheduleEntry{
+		schedule: schedule,
+		task:     task,
+	}
+
+	for _, config := range configs {
+		config(&e.configs)
+	}
+
+	s.entries[name] = e
+
+	if s.running {
+		s.startEntry(e)
+	}
+
+	return nil
+}
+
+// ScheduleCron registers the task to be run on the given cron expression with the given name.
+// See ParseCron for the supported expressions.
+func (s *Scheduler) ScheduleCron(name, expr string, task ScheduledTask, configs ...ScheduleConfigFunc) error {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return err
+	}
+
+	return s.Schedule(name, schedule, task, configs...)
+}
+
+// Unschedule removes the schedule with the given name, jobs those are already enqueued are not affected.
+// It returns false if there is no schedule with the given name.
+func (s *Scheduler) Unschedule(name string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return false
+	}
+
+	delete(s.entries, name)
+
+	if s.running {
+		e.close()
+	}
+
+	return true
+}
+
+// Start starts running all the registered schedules.
+func (s *Scheduler) Start() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.running {
+		return errSchedulerRunning
+	}
+
+	s.running = true
+
+	for _, e := range s.entries {
+		s.startEntry(e)
+	}
+
+	return nil
+}
+
+// Stop stops running all the schedules and waits until their loops are exited.
+// It doesn't wait for the enqueued jobs to be finished, but for the tasks those are running, e.g. blocked on a full queue.
+func (s *Scheduler) Stop() {
+	s.mx.Lock()
+
+	if !s.running {
+		s.mx.Unlock()
+		return
+	}
+
+	s.running = false
+
+	for _, e := range s.entries {
+		e.close()
+	}
+
+	s.mx.Unlock()
+	s.wg.Wait()
+}
+
+// IsRunning returns whether the scheduler is running.
+func (s *Scheduler) IsRunning() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.running
+}
+
+// startEntry starts the loop of the entry, must be called with the lock held
+func (s *Scheduler) startEntry(e *scheduleEntry) {
+	e.mx.Lock()
+	e.stop = make(chan struct{})
+	e.stopped = false
+	e.mx.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		e.loop()
+	}()
+}
+
+// loop waits for every next run of the schedule and triggers it until the entry is closed
+func (e *scheduleEntry) loop() {
+	next := e.schedule.Next(time.Now())
+
+	for !next.IsZero() {
+		timer := time.NewTimer(time.Until(next) + e.jitter())
+
+		select {
+		case <-e.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		e.trigger()
+
+		// compute the next run from the previous one to avoid drifting,
+		// unless the runs are missed (e.g. the system was suspended)
+		if next = e.schedule.Next(next); !next.IsZero() && next.Before(time.Now()) {
+			next = e.schedule.Next(time.Now())
+		}
+	}
+}
+
+func (e *scheduleEntry) jitter() time.Duration {
+	if e.configs.Jitter <= 0 {
+		return 0
+	}
+
+	return rand.N(e.configs.Jitter)
+}
+
+// trigger enqueues the job of a due run according to the overlap policy
+func (e *scheduleEntry) trigger() {
+	e.mx.Lock()
+
+	if e.running > 0 {
+		switch e.configs.Overlap {
+		case OverlapSkip:
+			e.mx.Unlock()
+			return
+		case OverlapQueue:
+			e.pending = true
+			e.mx.Unlock()
+			return
+		}
+	}
+
+	// the run is reserved under the lock, and the task runs without it, since it might block, e.g. on a full queue
+	e.running++
+	e.mx.Unlock()
+
+	e.enqueue()
+}
+
+// enqueue runs the task of a reserved run and waits for the job in background, must be called without the lock.
+// A panicking task is recovered, so it doesn't crash the scheduler, and the run is counted as failed.
+func (e *scheduleEntry) enqueue() {
+	var wait func()
+	var ok bool
+
+	if err := utils.WithSafe("scheduled task", func() {
+		wait, ok = e.task.Run()
+	}); err != nil || !ok {
+		e.finish()
+		return
+	}
+
+	go func() {
+		if wait != nil {
+			wait()
+		}
+
+		e.finish()
+	}()
+}
+
+// finish releases the reserved run, and runs the delayed run once the previous ones are finished
+func (e *scheduleEntry) finish() {
+	e.mx.Lock()
+
+	e.running--
+	next := e.running == 0 && e.pending && !e.stopped
+
+	if next {
+		e.pending = false
+		e.running++
+	}
+
+	e.mx.Unlock()
+
+	if next {
+		e.enqueue()
+	}
+}
+
+// close stops the loop of the entry and drops the delayed run if any
+func (e *scheduleEntry) close() {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if e.stopped {
+		return
+	}
+
+	e.stopped = true
+	e.pending = false
+	close(e.stop)
+}
@@ -0,0 +1,250 @@
+package varmq
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fastSchedule runs at a sub-second interval, which Every doesn't allow
+type fastSchedule time.Duration
+
+func (s fastSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("enqueues jobs on schedule", func(t *testing.T) {
+		var processed atomic.Int32
+		q := NewVoidWorker(func(_ string) {
+			processed.Add(1)
+		}).BindQueue()
+		defer q.Close()
+
+		s := NewScheduler()
+		assert.NoError(t, s.Schedule("tick", fastSchedule(20*time.Millisecond), QueueTask(q, "tick")))
+		assert.NoError(t, s.Start())
+		assert.True(t, s.IsRunning())
+
+		time.Sleep(110 * time.Millisecond)
+		s.Stop()
+		assert.False(t, s.IsRunning())
+		q.WaitUntilFinished()
+
+		count := processed.Load()
+		assert.GreaterOrEqual(t, count, int32(3))
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, count, processed.Load(), "no job should be enqueued after stop")
+	})
+
+	t.Run("priority queue task", func(t *testing.T) {
+		processed := make(chan int, 10)
+		q := NewVoidWorker(func(data int) {
+			processed <- data
+		}).BindPriorityQueue()
+		defer q.Close()
+
+		s := NewScheduler()
+		s.Schedule("tick", fastSchedule(10*time.Millisecond), PriorityQueueTask(q, 7, 1))
+		s.Start()
+		defer s.Stop()
+
+		select {
+		case v := <-processed:
+			assert.Equal(t, 7, v)
+		case <-time.After(time.Second):
+			t.Fatal("scheduled job should be processed")
+		}
+	})
+
+	t.Run("persistent queue task", func(t *testing.T) {
+		double := func(data int) (int, error) {
+			return data * 2, nil
+		}
+
+		s := NewScheduler()
+		q := NewWorker(double).WithPersistentQueue(newMockPersistentQueue())
+		defer q.Close()
+
+		assert.ErrorIs(t, s.Schedule("tick", fastSchedule(10*time.Millisecond), QueueTask(q, 1)), errNoJobIdGenerator)
+
+		var ids atomic.Int32
+		processed := make(chan int, 10)
+
+		q = NewWorker(func(data int) (int, error) {
+			processed <- data
+			return data * 2, nil
+		}, WithJobIdGenerator(func() string {
+			return fmt.Sprintf("job-%d", ids.Add(1))
+		})).WithPersistentQueue(newMockPersistentQueue())
+		defer q.Close()
+
+		assert.NoError(t, s.Schedule("tick", fastSchedule(10*time.Millisecond), QueueTask(q, 3)))
+		assert.NoError(t, s.Start())
+		defer s.Stop()
+
+		for range 2 {
+			select {
+			case v := <-processed:
+				assert.Equal(t, 3, v)
+			case <-time.After(time.Second):
+				t.Fatal("scheduled jobs should be processed by the persistent queue")
+			}
+		}
+	})
+
+	t.Run("panicking task doesn't crash the scheduler", func(t *testing.T) {
+		var runs atomic.Int32
+		task := TaskFunc(func() (func(), bool) {
+			runs.Add(1)
+			panic("boom")
+		})
+
+		s := NewScheduler()
+		assert.NoError(t, s.Schedule("panic", fastSchedule(5*time.Millisecond), task, WithOverlapPolicy(OverlapSkip)))
+		assert.NoError(t, s.Start())
+
+		assert.Eventually(t, func() bool { return runs.Load() >= 2 }, time.Second, time.Millisecond)
+		s.Stop()
+	})
+
+	t.Run("blocking task doesn't block unscheduling", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		task := TaskFunc(func() (func(), bool) {
+			started <- struct{}{}
+			<-release // e.g. adding into a full queue with OverflowBlock
+
+			return nil, true
+		})
+
+		s := NewScheduler()
+		assert.NoError(t, s.Schedule("block", fastSchedule(5*time.Millisecond), task, WithOverlapPolicy(OverlapQueue)))
+		assert.NoError(t, s.Start())
+		<-started
+
+		unscheduled := make(chan struct{})
+		go func() {
+			assert.True(t, s.Unschedule("block"))
+			close(unscheduled)
+		}()
+
+		select {
+		case <-unscheduled:
+		case <-time.After(time.Second):
+			t.Fatal("unscheduling should not wait for the blocked task")
+		}
+
+		// the loop exits once the blocked task returns
+		close(release)
+		s.Stop()
+	})
+
+	t.Run("duplicate names and invalid cron", func(t *testing.T) {
+		s := NewScheduler()
+		task := TaskFunc(func() (func(), bool) { return nil, true })
+
+		assert.NoError(t, s.Schedule("a", Every(time.Hour), task))
+		assert.ErrorIs(t, s.Schedule("a", Every(time.Hour), task), errScheduleExists)
+		assert.Error(t, s.ScheduleCron("b", "not a cron", task))
+		assert.NoError(t, s.ScheduleCron("b", "@daily", task))
+
+		assert.NoError(t, s.Start())
+		assert.ErrorIs(t, s.Start(), errSchedulerRunning)
+
+		assert.True(t, s.Unschedule("a"))
+		assert.False(t, s.Unschedule("a"))
+		s.Stop()
+	})
+
+	overlap := func(t *testing.T, policy OverlapPolicy) (runs, maxConcurrent int32) {
+		var running, maxRunning, count atomic.Int32
+		release := make(chan struct{})
+
+		task := TaskFunc(func() (func(), bool) {
+			count.Add(1)
+			if n := running.Add(1); n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+
+			return func() {
+				<-release
+				running.Add(-1)
+			}, true
+		})
+
+		s := NewScheduler()
+		s.Schedule("slow", fastSchedule(10*time.Millisecond), task, WithOverlapPolicy(policy))
+		s.Start()
+
+		time.Sleep(75 * time.Millisecond)
+		s.Stop()
+		close(release)
+		time.Sleep(20 * time.Millisecond)
+
+		return count.Load(), maxRunning.Load()
+	}
+
+	t.Run("overlap allow", func(t *testing.T) {
+		runs, maxConcurrent := overlap(t, OverlapAllow)
+		assert.Greater(t, runs, int32(1))
+		assert.Equal(t, runs, maxConcurrent, "all runs should overlap")
+	})
+
+	t.Run("overlap skip", func(t *testing.T) {
+		runs, maxConcurrent := overlap(t, OverlapSkip)
+		assert.Equal(t, int32(1), runs, "runs should be skipped while the previous one is running")
+		assert.Equal(t, int32(1), maxConcurrent)
+	})
+
+	t.Run("overlap queue", func(t *testing.T) {
+		var count atomic.Int32
+		release := make(chan struct{}, 10)
+		ran := make(chan struct{}, 10)
+
+		task := TaskFunc(func() (func(), bool) {
+			count.Add(1)
+			ran <- struct{}{}
+			return func() { <-release }, true
+		})
+
+		s := NewScheduler()
+		s.Schedule("slow", fastSchedule(10*time.Millisecond), task, WithOverlapPolicy(OverlapQueue))
+		s.Start()
+
+		<-ran
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(1), count.Load(), "runs should wait for the previous one")
+
+		release <- struct{}{}
+
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatal("queued run should be enqueued once the previous one is finished")
+		}
+
+		s.Stop()
+		release <- struct{}{}
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, int32(2), count.Load(), "missed runs should be coalesced into one")
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		e := &scheduleEntry{}
+		WithJitter(10 * time.Millisecond)(&e.configs)
+
+		for range 100 {
+			j := e.jitter()
+			assert.GreaterOrEqual(t, j, time.Duration(0))
+			assert.Less(t, j, 10*time.Millisecond)
+		}
+
+		WithJitter(-time.Second)(&e.configs)
+		assert.Equal(t, time.Duration(0), e.jitter())
+	})
+}
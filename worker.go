@@ -1,7 +1,9 @@
 package varmq
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,6 +21,16 @@ type WorkerErrFunc[T any] func(T) error
 // VoidWorkerFunc represents a function that processes a Job and returns nothing.
 type VoidWorkerFunc[T any] func(T)
 
+// WorkerCtxFunc represents a context-aware function that processes a Job and returns a result and an error.
+// The context is cancelled when the job is cancelled or times out, the job deadline is exceeded or the worker is stopped.
+type WorkerCtxFunc[T, R any] func(context.Context, T) (R, error)
+
+// WorkerErrCtxFunc represents a context-aware function that processes a Job and returns an error.
+type WorkerErrCtxFunc[T any] func(context.Context, T) error
+
+// VoidWorkerCtxFunc represents a context-aware function that processes a Job and returns nothing.
+type VoidWorkerCtxFunc[T any] func(context.Context, T)
+
 type status = uint32
 
 const (
@@ -33,6 +45,7 @@ var (
 	errRunningWorker     = errors.New("worker is already running")
 	errNotRunningWorker  = errors.New("worker is not running")
 	errSameConcurrency   = errors.New("worker already has the same concurrency")
+	errUnsupportedValue  = errors.New("unsupported payload type")
 )
 
 type worker[T, R any] struct {
@@ -44,8 +57,26 @@ type worker[T, R any] struct {
 	Cache           ICache
 	status          atomic.Uint32
 	jobPullNotifier utils.Notifier
+	notifierMx      sync.RWMutex
+	notifierClosed  bool
 	wg              sync.WaitGroup
 	tickers         []*time.Ticker
+	ctx             context.Context
+	cancelCtx       context.CancelFunc
+	delayed         *delayedJobs[T, R]
+	admissionMx     sync.Mutex
+	space           spaceSignal
+	hooks           eventHooks
+	metrics         workerMetrics
+	rateWaker       rateWaker
+	keys            *keyLimits[T, R]
+	batcher         *batcher[T, R]
+	deps            *dependencies[T, R]
+	pending         pendingIndex[T, R]
+	leases          leases
+	replies         replyQueues
+	// subscribed is true if the queue notifies the worker once an item is enqueued, e.g. a nacked one is redelivered
+	subscribed bool
 	configs
 }
 
@@ -74,6 +105,7 @@ type Worker[T, R any] interface {
 	// PauseAndWait pauses the worker and waits until all ongoing processes are done.
 	PauseAndWait()
 	// Stop stops the worker and waits until all ongoing processes are done to gracefully close the channels.
+	// The context passed to context-aware worker functions is cancelled before waiting.
 	// Time complexity: O(n) where n is the number of channels
 	Stop()
 	// Restart restarts the worker and initializes new worker goroutines based on the concurrency.
@@ -82,6 +114,26 @@ type Worker[T, R any] interface {
 	// Resume continues processing jobs those are pending in the queue.
 	// Time complexity: O(n) where n is the concurrency
 	Resume() error
+	// OnEnqueue registers a hook those is called when a job is enqueued.
+	// Hooks are called synchronously, so they should be fast. A panic inside a hook is recovered.
+	OnEnqueue(fn func(Event))
+	// OnStart registers a hook those is called when an attempt of a job is started.
+	OnStart(fn func(Event))
+	// OnSuccess registers a hook those is called when an attempt of a job is succeeded.
+	OnSuccess(fn func(Event))
+	// OnError registers a hook those is called when an attempt of a job is failed with an error.
+	OnError(fn func(Event))
+	// OnPanic registers a hook those is called when the worker function panicked during an attempt of a job.
+	OnPanic(fn func(Event))
+	// OnRetry registers a hook those is called when a failed job is scheduled to be retried.
+	OnRetry(fn func(Event))
+	// OnClose registers a hook those is called when a job is done, whether it's succeeded, failed, cancelled or rejected.
+	OnClose(fn func(Event))
+	// Events returns a channel those receives the lifecycle events of the jobs.
+	// The events are dropped while the channel buffer is full, so a slow receiver never blocks the worker.
+	Events() <-chan Event
+	// Metrics returns a snapshot of the counters, gauges and latency histograms of the worker.
+	Metrics() Metrics
 }
 
 // newWorker creates a new worker with the given worker function and configurations
@@ -103,10 +155,30 @@ func newWorker[T, R any](wf any, configs ...any) *worker[T, R] {
 	}
 
 	w.concurrency.Store(c.Concurrency)
+	w.resetContext()
+	w.delayed = newDelayedJobs(w.enqueueDelayedJob)
+	w.keys = newKeyLimits[T, R](c, w.notifyToPullNextJobs)
+	w.deps = newDependencies[T, R]()
+	w.initBatcher()
 
 	return w
 }
 
+// resetContext creates a fresh base context for the worker, every job context is derived from it
+// so that stopping the worker cancels all ongoing context-aware jobs at once
+func (w *worker[T, R]) resetContext() {
+	if w.cancelCtx != nil {
+		w.cancelCtx()
+	}
+
+	w.ctx, w.cancelCtx = context.WithCancel(context.Background())
+}
+
+// stopContext returns the base context of the worker, those is cancelled once the worker is stopped
+func (w *worker[T, R]) stopContext() context.Context {
+	return w.ctx
+}
+
 func (w *worker[T, R]) setQueue(q IBaseQueue) {
 	w.Queue = q
 }
@@ -125,10 +197,32 @@ func (w *worker[T, R]) isNullCache() bool {
 // Time complexity: O(1) per job
 func (w *worker[T, R]) spawnWorker(node *collections.Node[poolNode[T, R]]) {
 	for j := range node.Value.ch {
-		w.processSingleJob(j)
+		start := w.now()
+		res := w.processSingleJob(j)
+		duration := w.since(start)
+		err := res.err
+
+		if err == nil {
+			w.emit(EventSucceeded, j, processing, finished, duration, nil)
+			w.closeJob(j, nil)
+		} else if delay, ok := w.retryDelay(j, err); ok {
+			// the job will be processed again, so it's not finished yet
+			w.emitFailure(j, res, duration, queued)
+			w.keys.hold(j)
+			w.retryJob(j, err, delay)
+		} else {
+			j.SaveAndSendError(err)
+
+			// cancelled jobs are not failed, so they are not moved to the dead letter queue
+			if !errors.Is(err, ErrJobCancelled) {
+				w.nackJob(j, w.moveToDeadLetterQueue(j, err))
+			}
 
-		j.ChangeStatus(finished)
-		j.close()
+			w.emitFailure(j, res, duration, finished)
+			w.closeJob(j, err)
+		}
+
+		w.keys.release(j)
 		w.freePoolNode(node)            // push back the free channel to the stack to be used for the next job
 		w.CurProcessing.Add(^uint32(0)) // Decrement the processing counter
 		w.notifyToPullNextJobs()
@@ -137,57 +231,353 @@ func (w *worker[T, R]) spawnWorker(node *collections.Node[poolNode[T, R]]) {
 }
 
 // processSingleJob processes a single job using the appropriate worker function type
-// It handles all three worker function types (VoidWorkerFunc, WorkerErrFunc, WorkerFunc)
+// It handles all worker function types (VoidWorkerFunc, WorkerErrFunc, WorkerFunc and their context-aware variants)
 // and safely captures any panics that might occur during processing
-// It also sends any errors or results back to the job's result channel
-func (w *worker[T, R]) processSingleJob(j iJob[T, R]) {
+// It sends the result back to the job's result channel and returns the execution outcome
+func (w *worker[T, R]) processSingleJob(j iJob[T, R]) execution[R] {
+	j.NewAttempt()
+	w.emitEnqueued(j)
+	w.emit(EventStarted, j, queued, processing, w.since(j.QueuedAt()), nil)
+
+	timeout := j.Timeout()
+	if timeout <= 0 {
+		// jobs restored from persistent queues might not carry the worker default
+		timeout = w.configs.JobConfigs.Timeout
+	}
+
+	ctx := w.ctx
+
+	// a plain function never sees the context, it's only needed to stop waiting for it on a timeout or cancellation
+	if timeout > 0 || w.isCtxAware() {
+		var cancel context.CancelFunc
+		ctx, cancel = j.BindContext(w.ctx)
+		defer cancel()
+	}
+
+	var res execution[R]
+
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrJobTimeout)
+		defer cancel()
+
+		res = w.executeWithTimeout(ctx, j.Data())
+	} else {
+		res = w.execute(ctx, j.Data())
+	}
+
+	// the result of a cancelled job is discarded, even if the function didn't respect the context
+	if j.IsCancelled() {
+		return execution[R]{err: ErrJobCancelled}
+	}
+
+	if res.err == nil && res.hasResult {
+		j.SaveAndSendResult(res.result)
+	}
+
+	return res
+}
+
+// isCtxAware reports whether the worker function takes the context of the job
+func (w *worker[T, R]) isCtxAware() bool {
+	switch w.workerFunc.(type) {
+	case VoidWorkerCtxFunc[T], WorkerErrCtxFunc[T], WorkerCtxFunc[T, R]:
+		return true
+	default:
+		return false
+	}
+}
+
+// execution is the outcome of running the worker function once
+type execution[R any] struct {
+	result    R
+	hasResult bool
+	err       error
+	panicked  bool
+}
+
+// emitFailure emits the failed or panicked event of the job according to the execution
+func (w *worker[T, R]) emitFailure(j iJob[T, R], res execution[R], d time.Duration, to status) {
+	t := EventFailed
+	if res.panicked {
+		t = EventPanicked
+	}
+
+	w.emit(t, j, processing, to, d, res.err)
+}
+
+// execute runs the worker function with the given data, recovering from panics
+func (w *worker[T, R]) execute(ctx context.Context, data T) (res execution[R]) {
 	var panicErr error
-	var err error
 
 	switch worker := w.workerFunc.(type) {
 	case VoidWorkerFunc[T]:
 		panicErr = utils.WithSafe("void worker", func() {
-			worker(j.Data())
+			worker(data)
 		})
 
 	case WorkerErrFunc[T]:
 		panicErr = utils.WithSafe("error worker", func() {
-			err = worker(j.Data())
+			res.err = worker(data)
 		})
 
 	case WorkerFunc[T, R]:
 		panicErr = utils.WithSafe("worker", func() {
-			result, e := worker(j.Data())
-			if e != nil {
-				err = e
-			} else {
-				j.SaveAndSendResult(result)
-			}
+			res.result, res.err = worker(data)
+			res.hasResult = res.err == nil
+		})
+
+	case VoidWorkerCtxFunc[T]:
+		panicErr = utils.WithSafe("void worker", func() {
+			worker(ctx, data)
+		})
+
+	case WorkerErrCtxFunc[T]:
+		panicErr = utils.WithSafe("error worker", func() {
+			res.err = worker(ctx, data)
+		})
+
+	case WorkerCtxFunc[T, R]:
+		panicErr = utils.WithSafe("worker", func() {
+			res.result, res.err = worker(ctx, data)
+			res.hasResult = res.err == nil
 		})
+
+	case BatchWorkerFunc[T, R]:
+		// the panics are recovered by the batcher, since the function runs once for the whole batch
+		return w.batcher.submit(data)
+
 	default:
 		// Log or handle the invalid type to avoid silent failures
-		err = errInvalidWorkerType
+		res.err = errInvalidWorkerType
+	}
+
+	res.err = selectError(panicErr, res.err)
+	res.panicked = panicErr != nil
+
+	return res
+}
+
+// executeWithTimeout runs the worker function in background and stops waiting for it once the timeout is exceeded
+// or the job is cancelled, so the pool node can be freed even if the function never returns.
+func (w *worker[T, R]) executeWithTimeout(ctx context.Context, data T) (res execution[R]) {
+	done := make(chan execution[R], 1)
+
+	go func() {
+		done <- w.execute(ctx, data)
+	}()
+
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		select {
+		// prefer the result if the function returned at the same time
+		case res = <-done:
+		default:
+			if cause := context.Cause(ctx); errors.Is(cause, ErrJobTimeout) || errors.Is(cause, ErrJobCancelled) {
+				return execution[R]{err: cause}
+			}
+
+			// otherwise the context is cancelled by the worker or the job deadline, wait for the function to return
+			res = <-done
+		}
+	}
+
+	// a context-aware function might fail by the timeout before we notice it
+	if res.err != nil && errors.Is(context.Cause(ctx), ErrJobTimeout) {
+		res.err = ErrJobTimeout
+	}
+
+	return res
+}
+
+// retryDelay returns the backoff delay of the failed job according to its retry policy.
+// It returns false if the job can't be retried, e.g. no attempts left.
+func (w *worker[T, R]) retryDelay(j iJob[T, R], err error) (time.Duration, bool) {
+	if errors.Is(err, errInvalidWorkerType) || errors.Is(err, ErrJobCancelled) {
+		return 0, false
+	}
+
+	policy := j.RetryPolicy()
+
+	// jobs parsed from persistent queues carry the max attempts only, so the rest falls back to the worker's policy
+	if policy.MaxAttempts == 0 {
+		policy = w.configs.JobConfigs.Retry
+	} else if policy.restored {
+		policy.Backoff = w.configs.JobConfigs.Retry.Backoff
 	}
 
-	// send error if any
-	if err := selectError(panicErr, err); err != nil {
-		j.SaveAndSendError(err)
+	attempt := j.Attempts()
+
+	if !policy.enabled() || attempt >= policy.MaxAttempts {
+		return 0, false
+	}
+
+	return policy.delay(attempt), true
+}
+
+// retryJob schedules the failed job to be enqueued again after the given delay.
+func (w *worker[T, R]) retryJob(j iJob[T, R], err error, delay time.Duration) {
+	j.ChangeStatus(queued)
+	j.SetLastError(err)
+	w.emit(EventRetrying, j, processing, queued, delay, err)
+
+	if delay > 0 {
+		w.delayed.Add(j, time.Now().Add(delay))
+	} else {
+		w.enqueueDelayedJob(j)
+	}
+}
+
+// delayJob holds the job in the delayed jobs if it's not due yet, and returns true if it's held.
+// The delivery of a job those is not due is handed back to a nackable distributed queue instead, to be redelivered once it's due.
+func (w *worker[T, R]) delayJob(j iJob[T, R]) bool {
+	runAt := j.RunAt()
+
+	if !runAt.After(time.Now()) {
+		return false
+	}
+
+	if w.nackUntilDue(j, runAt) {
+		return true
 	}
+
+	w.delayed.Add(j, runAt)
+	return true
+}
+
+// canHandBack reports whether the not due jobs can be handed back to the queue, those notifies the worker once they're due
+func (w *worker[T, R]) canHandBack() bool {
+	_, ok := w.Queue.(INackable)
+	return ok && w.subscribed
+}
+
+// nackUntilDue negatively acknowledges the delivery of the job with a delay until it's due, and returns true if it's requeued
+func (w *worker[T, R]) nackUntilDue(j iJob[T, R], runAt time.Time) bool {
+	ackId := j.AckId()
+
+	if !w.canHandBack() || ackId == "" || !w.Queue.(INackable).Nack(ackId, true, time.Until(runAt)) {
+		return false
+	}
+
+	w.leases.release(ackId)
+	j.SetAckId("")
+	// the job is pending in the queue again
+	w.indexPendingJob(j)
+	w.notifySpace()
+	return true
+}
+
+// delayedFull reports whether the worker holds as many not due jobs as it can, for the queues those can't take them back
+func (w *worker[T, R]) delayedFull() bool {
+	if _, ok := w.Queue.(IAcknowledgeable); !ok || w.canHandBack() {
+		return false
+	}
+
+	return w.delayed.Len() >= delayLookahead
+}
+
+// enqueueDelayedJob enqueues the job those delay is over into the worker's queue
+// if it fails to enqueue, the job will be closed with its last error
+func (w *worker[T, R]) enqueueDelayedJob(j iJob[T, R]) {
+	j.SetQueuedAt(time.Now())
+
+	if w.requeueJob(j) {
+		w.keys.requeued(j)
+		return
+	}
+
+	err := selectError(j.LastError(), errors.New("failed to enqueue the delayed job"))
+	j.SaveAndSendError(err)
+	w.nackJob(j, w.moveToDeadLetterQueue(j, err))
+	w.closeJob(j, err)
+}
+
+// requeueJob enqueues the job again into the worker's queue.
+// For acknowledgeable queues the previous delivery is acknowledged afterward.
+func (w *worker[T, R]) requeueJob(j iJob[T, R]) bool {
+	// the ack id of the previous delivery must be taken before enqueueing, cause it'll be replaced on the next dequeue
+	ackId := j.AckId()
+
+	if !w.enqueueJob(j) {
+		return false
+	}
+
+	if q, ok := w.Queue.(IAcknowledgeable); ok && ackId != "" {
+		q.Acknowledge(ackId)
+		w.leases.release(ackId)
+	}
+
+	w.notifyToPullNextJobs()
+	return true
+}
+
+// enqueueJob enqueues the job into the worker's queue with its priority if it's a priority queue.
+// Acknowledgeable queues receive the serialized job.
+func (w *worker[T, R]) enqueueJob(j iJob[T, R]) bool {
+	var item any = j
+
+	if _, ok := w.Queue.(IAcknowledgeable); ok {
+		val, err := j.encode(w.configs.Codec)
+		if err != nil {
+			return false
+		}
+
+		item = val
+		j.SetInternalQueue(w.Queue)
+	}
+
+	ok := false
+
+	switch q := w.Queue.(type) {
+	case IQueue:
+		ok = q.Enqueue(item)
+	case IPriorityQueue:
+		ok = q.Enqueue(item, j.Priority())
+	}
+
+	if ok {
+		w.indexPendingJob(j)
+	}
+
+	return ok
 }
 
 // startEventLoop starts the event loop that processes pending jobs when workers become available
-// It continuously checks if the worker is running, has available capacity, and if there are jobs in the queue
+// It continuously checks if the worker is running, has available capacity, if there is a job to be dispatched
+// and if the rate limit allows the next job
 // When all conditions are met, it processes the next job in the queue
 func (w *worker[T, R]) startEventLoop() {
 	w.jobPullNotifier.Receive(func() {
-		for w.IsRunning() && w.CurProcessing.Load() < w.concurrency.Load() && w.Queue.Len() > 0 {
+		for w.canDispatch() {
 			w.processNextJob()
 		}
 	})
 }
 
+// canDispatch reports whether the next job can be dispatched now
+func (w *worker[T, R]) canDispatch() bool {
+	return w.IsRunning() && w.CurProcessing.Load() < w.concurrency.Load() && w.hasNextJob() && w.allowedByRateLimit()
+}
+
+// hasNextJob reports whether a parked job is available or the queue can be pulled
+func (w *worker[T, R]) hasNextJob() bool {
+	return w.keys.hasReady() || (w.Queue.Len() > 0 && !w.keys.full() && !w.delayedFull())
+}
+
 // processNextJob processes the next Job in the queue.
+// The parked jobs those keys became available are dispatched before pulling the queue.
 func (w *worker[T, R]) processNextJob() {
+	if j, ackId, ok := w.keys.popReady(); ok {
+		// the parked job is not pending anymore, like the dequeued ones
+		w.notifySpace()
+		w.dispatchJob(j, ackId)
+		return
+	}
+
+	if w.keys.full() || w.delayedFull() {
+		return
+	}
+
 	var v any
 	var ok bool
 	var ackId string
@@ -203,6 +593,7 @@ func (w *worker[T, R]) processNextJob() {
 		return
 	}
 
+	w.notifySpace()
 	w.wg.Add(1)
 	var j iJob[T, R]
 
@@ -213,36 +604,153 @@ func (w *worker[T, R]) processNextJob() {
 		j = value
 	case []byte:
 		var err error
-		if j, err = parseToJob[T, R](value); err != nil {
+		if j, err = parseToJob[T, R](value, w.configs.Codec); err != nil {
+			// done after the delivery is settled, so WaitUntilFinished doesn't return in between
+			w.quarantine(value, err, ackId)
+			w.wg.Done()
 			return
 		}
 
+		w.pending.remove(j.ID())
+
 		if cachedJob, ok := w.Cache.Load(j.ID()); ok {
 			j = cachedJob.(iJob[T, R])
 		} else {
-			w.Cache.Store(j.ID(), j)
+			w.cacheJob(j)
 			j.SetInternalQueue(w.Queue)
 		}
 	default:
+		// an adapter might hand back a value those is neither a job nor a payload, it's quarantined like an unparsable one
+		w.quarantine(rawPayload(value), fmt.Errorf("%w: %T", errUnsupportedValue, value), ackId)
+		w.wg.Done()
 		return
 	}
 
 	if j.IsClosed() {
+		w.skipJob(j, ackId)
+		return
+	}
+
+	// jobs dequeued from persistent queues might not be due yet, so hand them back or hold them until they're due
+	// the delivery of a held job will be acknowledged once the job is enqueued again
+	j.SetAckId(ackId)
+	w.keepLease(ackId)
+	if w.delayJob(j) {
 		w.wg.Done()
-		w.Cache.Delete(j.ID())
-		// process next Job recursively if the current one is closed
-		w.processNextJob()
+		return
+	}
+
+	// the job of a saturated key is held aside, so the next job in the queue can be dispatched
+	if !w.keys.allowed(j) {
+		w.keys.park(j, ackId)
+		return
+	}
+
+	w.dispatchJob(j, ackId)
+}
+
+// dispatchJob hands over the dequeued job to a free worker of the pool
+func (w *worker[T, R]) dispatchJob(j iJob[T, R], ackId string) {
+	if ok, finish := j.StartProcessing(); !ok {
+		// the job is cancelled right before processing
+		if finish {
+			j.SaveAndSendError(j.CancelCause())
+			w.closeJob(j, j.CancelCause())
+			ackId = ""
+		}
+
+		w.skipJob(j, ackId)
 		return
 	}
 
 	w.CurProcessing.Add(1)
-	j.ChangeStatus(processing)
-	j.SetAckId(ackId)
+	w.takeRateLimit(j)
+	w.keys.acquire(j)
 
 	// then job will be process by the processSingleJob function inside spawnWorker
 	w.pickNextChannel() <- j
 }
 
+// cacheJob stores the job in the cache to be found by its id, and makes it cancellable while it's pending
+func (w *worker[T, R]) cacheJob(j iJob[T, R]) {
+	j.OnCancel(func() { w.cancelPendingJob(j) })
+
+	if id := j.ID(); id != "" {
+		w.Cache.Store(id, j)
+	}
+}
+
+// skipJob drops the dequeued job those is closed or cancelled, and acknowledges its delivery if any
+func (w *worker[T, R]) skipJob(j iJob[T, R], ackId string) {
+	// done after pulling the next job, so the wait group doesn't drop to zero in between
+	defer w.wg.Done()
+	w.Cache.Delete(j.ID())
+
+	// the job might not be removed from the queue when it's cancelled, so acknowledge the delivery here
+	if q, ok := w.Queue.(IAcknowledgeable); ok && ackId != "" {
+		q.Acknowledge(ackId)
+		w.leases.release(ackId)
+	}
+
+	// process next Job recursively if the current one is skipped
+	w.processNextJob()
+}
+
+// cancelPendingJob removes the cancelled job from the pending jobs and finishes it
+func (w *worker[T, R]) cancelPendingJob(j iJob[T, R]) {
+	w.removeCancelledJob(j)()
+}
+
+// removeCancelledJob removes the cancelled job from the pending jobs, and returns a function to finish it.
+// The job is finished separately, so its hooks can be run once the locks held while removing are released.
+func (w *worker[T, R]) removeCancelledJob(j iJob[T, R]) (finish func()) {
+	parked := false
+
+	if w.delayed.Remove(j) || w.deps.remove(j) || w.removePendingJob(j) {
+		w.notifySpace()
+	} else if w.keys.remove(j) {
+		parked = true
+		w.notifySpace()
+	}
+
+	return func() {
+		j.SaveAndSendError(j.CancelCause())
+		w.closeJob(j, j.CancelCause())
+
+		// parked jobs are already pulled from the queue, so they're counted by the wait group
+		if parked {
+			w.wg.Done()
+		}
+	}
+}
+
+// removePendingJob removes the job from the queue if the queue supports removing items
+func (w *worker[T, R]) removePendingJob(j iJob[T, R]) bool {
+	q, ok := w.Queue.(IRemovable)
+	if !ok {
+		return false
+	}
+
+	id := j.ID()
+
+	removed := q.Remove(func(item any) bool {
+		switch v := item.(type) {
+		case iJob[T, R]:
+			return v == j
+		case []byte:
+			return id != "" && parseJobId(v, w.configs.Codec) == id
+		}
+
+		return false
+	})
+
+	if removed {
+		w.pending.remove(id)
+	}
+
+	return removed
+}
+
 func (w *worker[T, R]) freePoolNode(node *collections.Node[poolNode[T, R]]) {
 	// If worker timeout is enabled, update the last used time
 	enabledIdleWorkersRemover := w.configs.IdleWorkerExpiryDuration > 0
@@ -282,7 +790,30 @@ func (w *worker[T, R]) initPoolNode() *collections.Node[poolNode[T, R]] {
 
 // notifyToPullNextJobs notifies the pullNextJobs function to process the next Job.
 func (w *worker[T, R]) notifyToPullNextJobs() {
-	w.jobPullNotifier.Send()
+	w.notifierMx.RLock()
+	defer w.notifierMx.RUnlock()
+
+	// the notifier might be closed by Stop or Restart while a pool node is finishing its job
+	if !w.notifierClosed {
+		w.jobPullNotifier.Send()
+	}
+}
+
+// closeNotifier closes the job pull notifier to stop the event loop,
+// if renew is true, a new notifier is created to be used by the next event loop
+func (w *worker[T, R]) closeNotifier(renew bool) {
+	w.notifierMx.Lock()
+	defer w.notifierMx.Unlock()
+
+	if !w.notifierClosed {
+		w.jobPullNotifier.Close()
+	}
+
+	w.notifierClosed = !renew
+
+	if renew {
+		w.jobPullNotifier = utils.NewNotifier(1)
+	}
 }
 
 // goCleanupCache starts a background process that periodically cleans up finished jobs from the cache
@@ -378,10 +909,13 @@ func (w *worker[T, R]) start() error {
 	defer w.notifyToPullNextJobs()
 	defer w.status.Store(running)
 
+	w.resetContext()
+	w.delayed.Start()
 	go w.startEventLoop()
 
 	w.goCleanupCache()
 	w.goRemoveIdleWorkers()
+	w.goAutoscale()
 
 	// init the first worker by default
 	w.pool.PushNode(w.initPoolNode())
@@ -433,6 +967,7 @@ func (w *worker[T, R]) TunePool(concurrency int) error {
 
 func (w *worker[T, R]) Copy(config ...any) IWorkerBinder[T, R] {
 	c := mergeConfigs(w.configs, config...)
+	c.cloneStates(w.configs)
 
 	newWorker := &worker[T, R]{
 		workerFunc:      w.workerFunc,
@@ -447,6 +982,11 @@ func (w *worker[T, R]) Copy(config ...any) IWorkerBinder[T, R] {
 	}
 
 	newWorker.concurrency.Store(c.Concurrency)
+	newWorker.resetContext()
+	newWorker.delayed = newDelayedJobs(newWorker.enqueueDelayedJob)
+	newWorker.keys = newKeyLimits[T, R](c, newWorker.notifyToPullNextJobs)
+	newWorker.deps = newDependencies[T, R]()
+	newWorker.initBatcher()
 
 	return newQueues(newWorker)
 }
@@ -468,9 +1008,16 @@ func (w *worker[T, R]) Stop() {
 	defer w.status.Store(stopped)
 	w.stopTickers()
 
+	// cancel the context of all ongoing context-aware jobs so they can return early
+	w.cancelCtx()
+	w.delayed.Stop()
+
 	// wait until all ongoing processes are done to gracefully close the channels
-	w.jobPullNotifier.Close()
 	w.PauseAndWait()
+	w.closeNotifier(false)
+
+	// the held deliveries are left to be redelivered by the queue
+	w.leases.releaseAll()
 
 	// remove all nodes from the list and close the channels
 	for _, node := range w.pool.NodeSlice() {
@@ -486,8 +1033,7 @@ func (w *worker[T, R]) Restart() error {
 	// wait until all ongoing processes are done to gracefully close the channels if any.
 	w.PauseAndWait()
 	// close the old notifier to avoid routine leaks
-	w.jobPullNotifier.Close()
-	w.jobPullNotifier = utils.NewNotifier(1)
+	w.closeNotifier(true)
 
 	if err := w.start(); err != nil {
 		return err
@@ -539,7 +1085,7 @@ func (w *worker[T, R]) Resume() error {
 	}
 
 	w.status.Store(running)
-	w.jobPullNotifier.Send()
+	w.notifyToPullNextJobs()
 
 	return nil
 }
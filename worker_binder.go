@@ -98,41 +98,29 @@ type IWorkerBinder[T, R any] interface {
 	// Example usage:
 	//   persistentPriorityQueue := worker.WithPersistentPriorityQueue(persistentPriorityQueue)
 	WithPersistentPriorityQueue(pq IPersistentPriorityQueue) PersistentPriorityQueue[T, R]
-}
-
-// IVoidWorkerBinder extends IWorkerBinder with distributed queue capabilities
-// specifically for void workers that don't return results. This interface is specialized
-// for workers that perform actions but don't need to return any data, making them suitable
-// for distributed processing scenarios where results aren't needed or are handled externally.
-//
-// The void worker pattern is ideal for fire-and-forget operations like sending notifications,
-// updating external systems, or logging events where no response is required.
-type IVoidWorkerBinder[T any] interface {
-	IWorkerBinder[T, any]
 
-	// WithDistributedQueue binds the void worker to a DistributedQueue implementation.
+	// WithDistributedQueue binds the worker to a DistributedQueue implementation.
 	// Distributed queues allow job processing to be spread across multiple instances or processes,
-	// enabling horizontal scaling of workers. Since void workers don't need to return results,
-	// they are perfect for distributed processing scenarios.
+	// enabling horizontal scaling of workers. The results of the jobs are discarded unless the worker
+	// is configured with WithResultBackend, then the producers can fetch them by the job ids, see ResultOf.
 	//
 	// Parameters:
 	//   - dq IDistributedQueue: A distributed queue implementation that satisfies the IDistributedQueue interface.
 	//     This could be backed by Redis, RabbitMQ, Kafka, or any other distributed messaging system.
 	//
 	// Returns:
-	//   - DistributedQueue[T, any]: A distributed queue that can distribute jobs across multiple workers or instances.
+	//   - DistributedQueue[T, R]: A distributed queue that can distribute jobs across multiple workers or instances.
 	//
 	// Example usage:
 	//   distributedQueue := NewDistributedQueue() // satisfies IDistributedQueue interface,  might be backed by Redis or other systems
-	//   distributedQueue := voidWorker.WithDistributedQueue(distributedQueue)
+	//   distributedQueue := worker.WithDistributedQueue(distributedQueue)
 	//   distributedQueue.Add(data) // This job can be processed by any worker instance listening to this queue
-	WithDistributedQueue(dq IDistributedQueue) DistributedQueue[T, any]
+	WithDistributedQueue(dq IDistributedQueue) DistributedQueue[T, R]
 
-	// WithDistributedPriorityQueue binds the void worker to a DistributedPriorityQueue implementation.
+	// WithDistributedPriorityQueue binds the worker to a DistributedPriorityQueue implementation.
 	// This combines distributed processing with priority-based job ordering. Jobs are distributed
 	// across multiple instances but processed according to priority within each instance.
-	// This is ideal for scenarios where some jobs are more urgent than others, but still need
-	// to be processed in a distributed manner.
+	// Like WithDistributedQueue, the results are kept only if the worker is configured with WithResultBackend.
 	//
 	// Parameters:
 	//   - dq IDistributedPriorityQueue: A distributed priority queue implementation that satisfies
@@ -140,14 +128,23 @@ type IVoidWorkerBinder[T any] interface {
 	//     distributed messaging systems that support priority-based message ordering.
 	//
 	// Returns:
-	//   - DistributedPriorityQueue[T, any]: A distributed priority queue that can distribute jobs
+	//   - DistributedPriorityQueue[T, R]: A distributed priority queue that can distribute jobs
 	//     across multiple workers while maintaining priority ordering.
 	//
 	// Example usage:
 	//   priorityQueue := NewDistributedPriorityQueue() // satisfies IDistributedPriorityQueue interface, might be backed by Redis or other systems
-	//   distributedPriorityQueue := voidWorker.WithDistributedPriorityQueue(priorityQueue)
+	//   distributedPriorityQueue := worker.WithDistributedPriorityQueue(priorityQueue)
 	//   distributedPriorityQueue.Add(data, -1) // This job will be processed with higher priority
-	WithDistributedPriorityQueue(dq IDistributedPriorityQueue) DistributedPriorityQueue[T, any]
+	WithDistributedPriorityQueue(dq IDistributedPriorityQueue) DistributedPriorityQueue[T, R]
+}
+
+// IVoidWorkerBinder is the IWorkerBinder of the void workers those don't return results.
+// Void workers suit distributed processing scenarios where results aren't needed or are handled externally.
+//
+// The void worker pattern is ideal for fire-and-forget operations like sending notifications,
+// updating external systems, or logging events where no response is required.
+type IVoidWorkerBinder[T any] interface {
+	IWorkerBinder[T, any]
 }
 
 // workerBinder implements both IWorkerBinder and IVoidWorkerBinder interfaces
@@ -165,7 +162,6 @@ func newQueues[T, R any](worker *worker[T, R]) IWorkerBinder[T, R] {
 
 // newVoidQueues creates a void worker binder that implements the IVoidWorkerBinder interface
 // It is specifically for workers that don't return results (void workers)
-// This binder adds support for distributed queue types in addition to standard queue types
 func newVoidQueues[T any](worker *worker[T, any]) IVoidWorkerBinder[T] {
 	// We can return the same workerBinder type but with the IVoidWorkerBinder interface
 	// This works because workerBinder implements all methods of IVoidWorkerBinder
@@ -193,7 +189,7 @@ func (qs *workerBinder[T, R]) BindQueue() Queue[T, R] {
 // WithQueue binds an existing queue implementation to the worker
 // It starts the worker and returns a Queue interface to interact with the queue
 func (qs *workerBinder[T, R]) WithQueue(q IQueue) Queue[T, R] {
-	qs.worker.start()
+	defer qs.worker.start()
 
 	return newQueue(qs.worker, q)
 }
@@ -203,13 +199,13 @@ func (q *workerBinder[T, R]) BindPriorityQueue() PriorityQueue[T, R] {
 }
 
 func (q *workerBinder[T, R]) WithPriorityQueue(pq IPriorityQueue) PriorityQueue[T, R] {
-	q.worker.start()
+	defer q.worker.start()
 
 	return newPriorityQueue(q.worker, pq)
 }
 
 func (q *workerBinder[T, R]) WithPersistentQueue(pq IPersistentQueue) PersistentQueue[T, R] {
-	q.worker.start()
+	defer q.worker.start()
 	// if cache is not set, use sync.Map as the default cache, we need it for persistent queue
 	if q.worker.isNullCache() {
 		q.setCache(new(sync.Map))
@@ -219,7 +215,7 @@ func (q *workerBinder[T, R]) WithPersistentQueue(pq IPersistentQueue) Persistent
 }
 
 func (q *workerBinder[T, R]) WithPersistentPriorityQueue(pq IPersistentPriorityQueue) PersistentPriorityQueue[T, R] {
-	q.worker.start()
+	defer q.worker.start()
 	// if cache is not set, use sync.Map as the default cache, we need it for persistent queue
 	if q.worker.isNullCache() {
 		q.setCache(new(sync.Map))
@@ -229,18 +225,20 @@ func (q *workerBinder[T, R]) WithPersistentPriorityQueue(pq IPersistentPriorityQ
 }
 
 func (qs *workerBinder[T, R]) WithDistributedQueue(dq IDistributedQueue) DistributedQueue[T, R] {
-	qs.worker.start()
 	defer dq.Subscribe(qs.handleQueueSubscription)
+	defer qs.worker.start()
+	qs.worker.subscribed = true
 
-	queue := NewDistributedQueue[T, R](dq)
+	queue := NewDistributedQueue[T, R](dq, WithDistributedCodec(qs.worker.configs.Codec))
 	qs.worker.setQueue(dq)
 	return queue
 }
 
 func (qs *workerBinder[T, R]) WithDistributedPriorityQueue(dq IDistributedPriorityQueue) DistributedPriorityQueue[T, R] {
-	qs.worker.start()
 	defer dq.Subscribe(qs.handleQueueSubscription)
+	defer qs.worker.start()
 	defer qs.worker.setQueue(dq)
+	qs.worker.subscribed = true
 
-	return NewDistributedPriorityQueue[T, R](dq)
+	return NewDistributedPriorityQueue[T, R](dq, WithDistributedCodec(qs.worker.configs.Codec))
 }
@@ -1,8 +1,11 @@
 package varmq
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -521,4 +524,299 @@ func TestTunePool(t *testing.T) {
 		// Verify concurrency remains unchanged
 		assert.Equal(t, initialConcurrency, w.NumConcurrency(), "Concurrency should remain unchanged when set to same value")
 	})
+
+	t.Run("with autoscaler", func(t *testing.T) {
+		release := make(chan struct{})
+		w := NewVoidWorker(func(_ int) {
+			<-release
+		}, WithAutoscaler(&AIMDPolicy{Min: 1, Max: 4}), WithAutoscaleInterval(10*time.Millisecond))
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		for i := range 10 {
+			q.Add(i)
+		}
+
+		assert.Eventually(t, func() bool {
+			return w.NumConcurrency() == 4 && w.NumProcessing() == 4
+		}, time.Second, 10*time.Millisecond, "concurrency should grow to max while jobs are waiting")
+
+		close(release)
+
+		assert.Eventually(t, func() bool {
+			return w.NumConcurrency() == 1
+		}, time.Second, 10*time.Millisecond, "concurrency should shrink to min once the worker is idle")
+	})
+}
+
+func TestContextAwareWorker(t *testing.T) {
+	t.Run("context is passed to the worker function", func(t *testing.T) {
+		var wf WorkerCtxFunc[string, int] = func(ctx context.Context, data string) (int, error) {
+			if ctx == nil {
+				return 0, errors.New("context should not be nil")
+			}
+
+			return len(data), nil
+		}
+
+		q := NewWorkerCtx(wf).BindQueue()
+		defer q.Close()
+
+		job, ok := q.Add("hello")
+		assert.True(t, ok, "job should be added successfully")
+
+		result, err := job.Result()
+		assert.NoError(t, err, "job should be processed without error")
+		assert.Equal(t, 5, result, "result should match the length of input")
+	})
+
+	t.Run("context is cancelled when the worker is stopped", func(t *testing.T) {
+		started := make(chan struct{})
+		var wf WorkerErrCtxFunc[int] = func(ctx context.Context, _ int) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		q := NewErrWorkerCtx(wf).BindQueue()
+
+		job, ok := q.Add(1)
+		assert.True(t, ok, "job should be added successfully")
+
+		<-started
+
+		stopped := make(chan struct{})
+		go func() {
+			q.Worker().Stop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("stop should not wait for the context-aware job to finish by itself")
+		}
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, context.Canceled, "job should return the context cancellation error")
+	})
+
+	t.Run("context is cancelled when the job deadline is exceeded", func(t *testing.T) {
+		var wf VoidWorkerCtxFunc[int] = func(ctx context.Context, _ int) {
+			<-ctx.Done()
+		}
+
+		q := NewVoidWorkerCtx(wf).BindQueue()
+		defer q.Close()
+
+		job, ok := q.Add(1, WithJobDeadline(time.Now().Add(50*time.Millisecond)))
+		assert.True(t, ok, "job should be added successfully")
+
+		done := make(chan struct{})
+		go func() {
+			job.Result()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("job should be finished after the deadline is exceeded")
+		}
+	})
+}
+
+func TestJobTimeout(t *testing.T) {
+	t.Run("hung job times out and frees the pool slot", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		q := NewWorker(func(data int) (int, error) {
+			if data == 1 {
+				<-release
+			}
+
+			return data, nil
+		}, 1, WithJobTimeout(50*time.Millisecond)).BindQueue()
+		defer q.Close()
+
+		hung, _ := q.Add(1)
+		next, _ := q.Add(2)
+
+		_, err := hung.Result()
+		assert.ErrorIs(t, err, ErrJobTimeout)
+
+		result, err := next.Result()
+		assert.NoError(t, err, "next job should be processed by the freed slot")
+		assert.Equal(t, 2, result)
+
+		q.WaitUntilFinished()
+		assert.Equal(t, 0, q.Worker().NumProcessing())
+	})
+
+	t.Run("context is cancelled on timeout", func(t *testing.T) {
+		cancelled := make(chan error, 1)
+		var wf WorkerErrCtxFunc[int] = func(ctx context.Context, _ int) error {
+			<-ctx.Done()
+			cancelled <- context.Cause(ctx)
+			return ctx.Err()
+		}
+
+		q := NewErrWorkerCtx(wf).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1, WithJobTimeout(30*time.Millisecond))
+
+		_, err := job.Result()
+		assert.ErrorIs(t, err, ErrJobTimeout)
+		assert.ErrorIs(t, <-cancelled, ErrJobTimeout)
+	})
+
+	t.Run("job timeout overrides the worker timeout", func(t *testing.T) {
+		q := NewWorker(func(data int) (int, error) {
+			time.Sleep(50 * time.Millisecond)
+			return data, nil
+		}, WithJobTimeout(10*time.Millisecond)).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(1, WithJobTimeout(time.Second))
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result)
+	})
+
+	t.Run("timed out job is retried", func(t *testing.T) {
+		var calls atomic.Int32
+		q := NewWorker(func(data int) (int, error) {
+			if calls.Add(1) == 1 {
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			return data, nil
+		}, WithJobTimeout(30*time.Millisecond), WithRetry(2, nil)).BindQueue()
+		defer q.Close()
+
+		job, _ := q.Add(5)
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 5, result)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestBatchWorker(t *testing.T) {
+	t.Run("processes the jobs in batches", func(t *testing.T) {
+		var mx sync.Mutex
+		sizes := make([]int, 0)
+
+		w := NewBatchWorker(func(data []int) ([]int, error) {
+			mx.Lock()
+			sizes = append(sizes, len(data))
+			mx.Unlock()
+
+			results := make([]int, len(data))
+			for i, v := range data {
+				results[i] = v * 2
+			}
+
+			return results, nil
+		}, WithBatchSize(5), WithBatchLinger(time.Second))
+
+		assert.Equal(t, 5, w.NumConcurrency(), "the concurrency should be raised to the batch size")
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		jobs := make([]EnqueuedJob[int], 0, 10)
+		for i := range 10 {
+			job, _ := q.Add(i)
+			jobs = append(jobs, job)
+		}
+
+		for i, job := range jobs {
+			result, err := job.Result()
+			assert.NoError(t, err)
+			assert.Equal(t, i*2, result)
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+		assert.Equal(t, []int{5, 5}, sizes)
+	})
+
+	t.Run("doesn't wait for more jobs without a linger", func(t *testing.T) {
+		w := NewBatchWorker(func(data []int) ([]int, error) {
+			return data, nil
+		}, WithBatchSize(100))
+
+		q := w.BindQueue()
+		defer q.Close()
+
+		start := time.Now()
+		job, _ := q.Add(1)
+
+		result, err := job.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result)
+		assert.Less(t, time.Since(start), maxBatchWait)
+	})
+
+	t.Run("retries the failed jobs of a batch on their own", func(t *testing.T) {
+		var calls atomic.Int32
+
+		w := NewBatchWorker(func(data []int) ([]int, error) {
+			errs := make(BatchErrors, len(data))
+			if calls.Add(1) == 1 {
+				for i, v := range data {
+					if v == 1 {
+						errs[i] = errors.New("failed")
+					}
+				}
+			}
+
+			return data, errs
+		}, WithBatchSize(2), WithBatchLinger(20*time.Millisecond), WithRetry(2, nil))
+
+		q := w.BindQueue()
+		w.Pause()
+		defer q.Close()
+
+		first, _ := q.Add(0)
+		second, _ := q.Add(1)
+		assert.NoError(t, w.Resume())
+
+		for i, job := range []EnqueuedJob[int]{first, second} {
+			result, err := job.Result()
+			assert.NoError(t, err)
+			assert.Equal(t, i, result)
+		}
+
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestWorkerCopyStates(t *testing.T) {
+	policy := &GradientPolicy{Min: 1, Max: 4}
+	limiter := NewSlidingWindow(2, time.Second)
+	w := newWorker[int, int](func(j iJob[int, int]) {}, WithAutoscaler(policy), WithRateLimiter(limiter))
+
+	t.Run("copies get their own built-in policy and limiter", func(t *testing.T) {
+		limiter.Take(2)
+
+		c := w.Copy().(*workerBinder[int, int])
+		assert.NotSame(t, policy, c.configs.Autoscaler)
+		assert.Equal(t, &GradientPolicy{Min: 1, Max: 4}, c.configs.Autoscaler)
+		assert.NotSame(t, limiter, c.configs.RateLimiter)
+		assert.Zero(t, c.configs.RateLimiter.(*slidingWindow).total, "should start with an empty window")
+	})
+
+	t.Run("replaced ones are kept", func(t *testing.T) {
+		other := NewTokenBucket(1, time.Second, 1)
+
+		c := w.Copy(WithRateLimiter(other)).(*workerBinder[int, int])
+		assert.Same(t, other, c.configs.RateLimiter)
+	})
 }
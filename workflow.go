@@ -0,0 +1,270 @@
+package varmq
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// workflowRuns numbers the runs of the workflows, so the job ids of different runs never collide
+var workflowRuns atomic.Uint64
+
+// WorkflowQueue is the queue a workflow adds its jobs into, e.g. Queue or PriorityQueue.
+// Persistent queues reject the jobs those depend on others, see ErrPersistentDependency.
+type WorkflowQueue[T, R any] interface {
+	Add(data T, configs ...JobConfigFunc) (EnqueuedJob[R], bool)
+}
+
+// Workflow builds a graph of jobs, those run once the jobs they depend on succeed, e.g. the steps of an ETL pipeline.
+// The jobs of a workflow must be processed by the same worker, since the dependencies are tracked by the worker.
+//
+// Example:
+//
+//	wf := NewWorkflow[string, int]("etl")
+//	wf.Step("extract", "s3://bucket/input")
+//	wf.Step("transform", "normalize", "extract")
+//	wf.Step("load", "warehouse", "transform").Compensate("rollback")
+//
+//	run, err := wf.Run(queue)
+//	results := run.Results()
+type Workflow[T, R any] struct {
+	name  string
+	steps []*WorkflowStep[T]
+	index map[string]*WorkflowStep[T]
+	err   error
+}
+
+// WorkflowStep is a job of a workflow.
+type WorkflowStep[T any] struct {
+	name         string
+	data         T
+	dependsOn    []string
+	policy       ParentFailurePolicy
+	compensation *T
+	configs      []JobConfigFunc
+}
+
+// WorkflowRun is a running workflow, holding the jobs of its steps.
+type WorkflowRun[R any] struct {
+	id            string
+	steps         []string
+	jobs          map[string]EnqueuedJob[R]
+	compensations map[string]EnqueuedJob[R]
+	mx            sync.Mutex
+}
+
+// NewWorkflow creates an empty workflow with the given name, those prefixes the job ids of its runs.
+func NewWorkflow[T, R any](name string) *Workflow[T, R] {
+	return &Workflow[T, R]{
+		name:  name,
+		index: make(map[string]*WorkflowStep[T]),
+	}
+}
+
+// Step adds a step with the given data, those runs once the given steps succeed.
+// The steps it depends on might be added later, but before running the workflow.
+func (wf *Workflow[T, R]) Step(name string, data T, dependsOn ...string) *WorkflowStep[T] {
+	step := &WorkflowStep[T]{name: name, data: data, dependsOn: dependsOn}
+
+	if _, ok := wf.index[name]; ok {
+		wf.err = errors.Join(wf.err, fmt.Errorf("workflow step %q is duplicated", name))
+		return step
+	}
+
+	wf.steps = append(wf.steps, step)
+	wf.index[name] = step
+
+	return step
+}
+
+// OnParentFailure sets what happens to the step when one of the steps it depends on fails,
+// default is CancelOnParentFailure.
+func (s *WorkflowStep[T]) OnParentFailure(policy ParentFailurePolicy) *WorkflowStep[T] {
+	s.policy = policy
+	return s
+}
+
+// Compensate adds a job with the given data instead of running the step when one of the steps it depends on fails.
+// The step itself is cancelled with ErrParentFailed.
+func (s *WorkflowStep[T]) Compensate(data T) *WorkflowStep[T] {
+	s.policy = CompensateOnParentFailure
+	s.compensation = &data
+	return s
+}
+
+// With sets the configs of the job of the step, e.g. WithRetry or WithJobTimeout.
+func (s *WorkflowStep[T]) With(configs ...JobConfigFunc) *WorkflowStep[T] {
+	s.configs = append(s.configs, configs...)
+	return s
+}
+
+// sort returns the steps those every step comes after the steps it depends on,
+// and an error if a step depends on an unknown step or the steps depend on each other in a cycle.
+func (wf *Workflow[T, R]) sort() ([]*WorkflowStep[T], error) {
+	if wf.err != nil {
+		return nil, wf.err
+	}
+
+	waiting := make(map[string]int, len(wf.steps))
+	children := make(map[string][]*WorkflowStep[T], len(wf.steps))
+	ready := make([]*WorkflowStep[T], 0, len(wf.steps))
+
+	for _, step := range wf.steps {
+		for _, parent := range step.dependsOn {
+			if _, ok := wf.index[parent]; !ok {
+				return nil, fmt.Errorf("workflow step %q depends on unknown step %q", step.name, parent)
+			}
+
+			children[parent] = append(children[parent], step)
+		}
+
+		waiting[step.name] = len(step.dependsOn)
+
+		if len(step.dependsOn) == 0 {
+			ready = append(ready, step)
+		}
+	}
+
+	sorted := make([]*WorkflowStep[T], 0, len(wf.steps))
+
+	for len(ready) > 0 {
+		step := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, step)
+
+		for _, child := range children[step.name] {
+			if waiting[child.name]--; waiting[child.name] == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+
+	if len(sorted) != len(wf.steps) {
+		return nil, errors.New("workflow steps depend on each other in a cycle")
+	}
+
+	return sorted, nil
+}
+
+// Run adds the jobs of the steps into the given queue, and returns the run to wait for them.
+// It returns an error without adding any job if the graph of the steps is invalid.
+// The queue must be bound to the worker those processes the jobs, the jobs depending on each other are held by it.
+func (wf *Workflow[T, R]) Run(q WorkflowQueue[T, R]) (*WorkflowRun[R], error) {
+	sorted, err := wf.sort()
+	if err != nil {
+		return nil, err
+	}
+
+	run := &WorkflowRun[R]{
+		id:            fmt.Sprintf("%s/%d", wf.name, workflowRuns.Add(1)),
+		steps:         make([]string, 0, len(sorted)),
+		jobs:          make(map[string]EnqueuedJob[R], len(sorted)),
+		compensations: make(map[string]EnqueuedJob[R]),
+	}
+
+	// the dependents are added first, so they're waiting before their parents can finish
+	for i := len(sorted) - 1; i >= 0; i-- {
+		step := sorted[i]
+		configs := append([]JobConfigFunc{WithJobId(run.jobId(step.name))}, step.configs...)
+
+		if len(step.dependsOn) > 0 {
+			parents := make([]string, len(step.dependsOn))
+			for i, parent := range step.dependsOn {
+				parents[i] = run.jobId(parent)
+			}
+
+			configs = append(configs, WithDependsOn(parents...), WithParentFailurePolicy(step.policy))
+		}
+
+		if step.compensation != nil {
+			configs = append(configs, WithCompensation(compensate(run, q, step.name, *step.compensation)))
+		}
+
+		run.steps = append(run.steps, step.name)
+
+		// a rejected job is still returned to report the reason, only a job those can't be created at all is nil
+		if job, _ := q.Add(step.data, configs...); job != nil {
+			run.jobs[step.name] = job
+		}
+	}
+
+	return run, nil
+}
+
+// jobId returns the job id of the given step
+func (r *WorkflowRun[R]) jobId(step string) string {
+	return fmt.Sprintf("%s/%s", r.id, step)
+}
+
+// compensate returns the function those adds the compensation job of the step into the queue
+func compensate[T, R any](r *WorkflowRun[R], q WorkflowQueue[T, R], step string, data T) func() {
+	return func() {
+		job, _ := q.Add(data, WithJobId(r.jobId(step)+"/compensation"))
+
+		if job != nil {
+			r.mx.Lock()
+			r.compensations[step] = job
+			r.mx.Unlock()
+		}
+	}
+}
+
+// ID returns the id of the run, those prefixes the job ids of its steps.
+func (r *WorkflowRun[R]) ID() string {
+	return r.id
+}
+
+// Job returns the job of the given step.
+func (r *WorkflowRun[R]) Job(step string) (EnqueuedJob[R], bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	job, ok := r.jobs[step]
+	return job, ok
+}
+
+// Compensation returns the compensation job of the given step, once it's added.
+func (r *WorkflowRun[R]) Compensation(step string) (EnqueuedJob[R], bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	job, ok := r.compensations[step]
+	return job, ok
+}
+
+// Results waits until all the steps are finished and returns their results by the step names.
+func (r *WorkflowRun[R]) Results() map[string]Result[R] {
+	results := make(map[string]Result[R], len(r.steps))
+
+	for _, step := range r.steps {
+		job, ok := r.Job(step)
+		if !ok {
+			results[step] = Result[R]{JobId: r.jobId(step), Err: errEnqueueFailed}
+			continue
+		}
+
+		data, err := job.Result()
+		results[step] = Result[R]{JobId: job.ID(), Data: data, Err: err}
+	}
+
+	return results
+}
+
+// Cancel cancels all the pending and running steps of the run.
+// It returns an error if there is no step left to cancel.
+func (r *WorkflowRun[R]) Cancel() error {
+	cancelled := 0
+
+	for _, step := range r.steps {
+		if job, ok := r.Job(step); ok && job.Cancel() == nil {
+			cancelled++
+		}
+	}
+
+	if cancelled == 0 {
+		return errors.New("workflow has no step left to cancel")
+	}
+
+	return nil
+}
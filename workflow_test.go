@@ -0,0 +1,111 @@
+package varmq
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflow(t *testing.T) {
+	t.Run("runs the steps after the steps they depend on", func(t *testing.T) {
+		var mx sync.Mutex
+		order := make([]string, 0, 4)
+
+		q := NewWorker(func(data string) (string, error) {
+			mx.Lock()
+			defer mx.Unlock()
+
+			order = append(order, data)
+			return data + "!", nil
+		}, WithConcurrency(4)).BindQueue()
+		defer q.Close()
+
+		wf := NewWorkflow[string, string]("etl")
+		wf.Step("load", "load", "transform-a", "transform-b")
+		wf.Step("transform-a", "transform-a", "extract")
+		wf.Step("transform-b", "transform-b", "extract")
+		wf.Step("extract", "extract")
+
+		run, err := wf.Run(q)
+		assert.NoError(t, err)
+
+		job, ok := run.Job("extract")
+		assert.True(t, ok)
+		assert.Equal(t, run.ID()+"/extract", job.ID())
+
+		results := run.Results()
+		assert.Len(t, results, 4)
+		for step, res := range results {
+			assert.NoError(t, res.Err)
+			assert.Equal(t, step+"!", res.Data)
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+		assert.Equal(t, "extract", order[0])
+		assert.Equal(t, "load", order[3])
+	})
+
+	t.Run("compensates the failed steps", func(t *testing.T) {
+		failure := errors.New("failed")
+
+		q := NewWorker(func(data string) (string, error) {
+			if data == "transform" {
+				return "", failure
+			}
+
+			return data, nil
+		}).BindQueue()
+		defer q.Close()
+
+		wf := NewWorkflow[string, string]("etl")
+		wf.Step("transform", "transform")
+		wf.Step("load", "load", "transform").Compensate("rollback")
+
+		run, err := wf.Run(q)
+		assert.NoError(t, err)
+
+		results := run.Results()
+		assert.ErrorIs(t, results["transform"].Err, failure)
+		assert.ErrorIs(t, results["load"].Err, ErrParentFailed)
+
+		assert.Eventually(t, func() bool {
+			_, ok := run.Compensation("load")
+			return ok
+		}, time.Second, time.Millisecond)
+
+		compensation, _ := run.Compensation("load")
+		result, err := compensation.Result()
+		assert.NoError(t, err)
+		assert.Equal(t, "rollback", result)
+	})
+
+	t.Run("rejects invalid graphs", func(t *testing.T) {
+		q := NewWorker(func(data string) (string, error) {
+			return data, nil
+		}).BindQueue()
+		defer q.Close()
+
+		wf := NewWorkflow[string, string]("unknown")
+		wf.Step("a", "a", "missing")
+		_, err := wf.Run(q)
+		assert.ErrorContains(t, err, "unknown step")
+
+		wf = NewWorkflow[string, string]("cycle")
+		wf.Step("a", "a", "b")
+		wf.Step("b", "b", "a")
+		_, err = wf.Run(q)
+		assert.ErrorContains(t, err, "cycle")
+
+		wf = NewWorkflow[string, string]("duplicated")
+		wf.Step("a", "a")
+		wf.Step("a", "a")
+		_, err = wf.Run(q)
+		assert.ErrorContains(t, err, "duplicated")
+
+		assert.Zero(t, q.NumPending(), "no job should be added")
+	})
+}